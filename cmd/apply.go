@@ -1,85 +1,521 @@
 package cmd
 
 import (
-	"bytes"
+	"errors"
 	"fmt"
-	"log"
-	"os"
+	"log/slog"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
 
+	defaultconfigs "github.com/cmmarslender/edgefig/default-configs"
+	"github.com/cmmarslender/edgefig/internal/backup"
 	"github.com/cmmarslender/edgefig/internal/connection"
-	"github.com/cmmarslender/edgefig/internal/util"
+	"github.com/cmmarslender/edgefig/internal/health"
 	"github.com/cmmarslender/edgefig/pkg/config"
 	"github.com/cmmarslender/edgefig/pkg/edgeconfig"
 	"github.com/cmmarslender/edgefig/pkg/translate"
 )
 
+var (
+	applyBatchSize         int
+	applyCanary            string
+	applyHealthCheck       bool
+	applyHealthTimeout     time.Duration
+	applyHealthInterval    time.Duration
+	applyRollbackOnFailure bool
+	applyIncremental       bool
+	applyMaxIncremental    int
+	applyParallel          int
+)
+
 // applyCmd represents the apply command
 var applyCmd = &cobra.Command{
 	Use:   "apply",
 	Short: "Applies the configuration to all devices",
+	Long: `Applies the configuration to all devices
+
+By default every selected router is applied in a single batch. Use --canary to apply a single router first,
+and --batch-size to roll out to the rest in waves. When rolling out in stages, every router in a batch must pass
+its health checks (ssh reachability, configured interfaces up, bgp sessions established, dhcp server running)
+before the next batch starts. Use --health-check to run the same checks after a single batch apply.
+
+A full apply loads the entire generated config, which reloads every service on the router. Use --incremental to
+compare the live config against the generated config and only run the set/delete commands needed, falling back
+to a full load when there are more than --max-incremental-changes commands. Use "edgefig plan" to preview them.
+
+Routers within a batch are applied one at a time. Use --parallel to apply more of them at once.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		cfg, err := config.LoadConfig(viper.GetString("config"))
+		result := newResult("apply")
+		cfg, routers, err := loadSelectedRouters()
 		if err != nil {
-			log.Fatalln(err.Error())
+			failResult(result, codeConfig, err)
 		}
 
-		// @TODO should iterate all devices that come back in edgecfg and apply
-		connDeets := cfg.Routers[0].Connection
-		ssh, err := connection.NewSSHConnection(connDeets.IP, connDeets.Port, connDeets.Username, connDeets.Password)
+		batches, err := planBatches(routers, applyCanary, applyBatchSize)
 		if err != nil {
-			log.Fatalln(err.Error())
+			failResult(result, codeConfig, err)
+		}
+		if applyParallel < 1 {
+			failResult(result, codeConfig, fmt.Errorf("parallel must be at least 1"))
 		}
+		staged := len(batches) > 1
+		runChecks := staged || applyHealthCheck
 
-		availableInterfaces, err := ssh.GetAvailablePorts()
-		if err != nil {
-			log.Fatalf("Error getting available ports: %s\n", err.Error())
+		devices := map[string]*DeviceResult{}
+		for _, batch := range batches {
+			for _, router := range batch {
+				devices[router.Name] = result.device(router.Name)
+			}
 		}
 
-		edgecfg, err := translate.ConfigToEdgeConfig(cfg, availableInterfaces)
-		if err != nil {
-			log.Fatalln(err.Error())
+		for batchIdx, batch := range batches {
+			if staged {
+				slog.Info("applying batch", "batch", batchIdx+1, "batches", len(batches), "routers", routerNames(batch))
+			}
+
+			err = rolloutBatch(cfg, batch, devices, runChecks)
+			if err != nil {
+				slog.Error("halting rollout", "batch", batchIdx+1, "error", err.Error())
+				break
+			}
 		}
 
-		marshalled, err := edgeconfig.Marshal(edgecfg)
-		if err != nil {
-			log.Fatalln(err.Error())
+		finishResult(result, func(result *Result) {
+			for _, device := range result.Devices {
+				switch {
+				case device.Error != nil:
+					fmt.Printf("%s: %s (%s)\n", device.Name, device.Status, device.Error.Message)
+				case device.Status == statusChanged:
+					fmt.Printf("%s: %s (%s, %d changes)\n", device.Name, device.Status, device.Mode, len(device.Changes))
+				default:
+					fmt.Printf("%s: %s\n", device.Name, device.Status)
+				}
+			}
+		})
+	},
+}
+
+// planBatches splits the routers into the batches they will be rolled out in
+// The canary, if set, is always alone in the first batch. A batch size of 0 puts all remaining routers in one batch
+func planBatches(routers []config.Router, canary string, batchSize int) ([][]config.Router, error) {
+	if batchSize < 0 {
+		return nil, fmt.Errorf("batch size must not be negative")
+	}
+
+	var batches [][]config.Router
+	remaining := routers
+
+	if canary != "" {
+		remaining = nil
+		for _, router := range routers {
+			if router.Name == canary {
+				batches = append(batches, []config.Router{router})
+			} else {
+				remaining = append(remaining, router)
+			}
+		}
+		if len(batches) == 0 {
+			return nil, fmt.Errorf("canary %s is not one of the selected routers", canary)
 		}
+	}
 
-		live, err := ssh.FetchLiveConfig()
-		if err != nil {
-			log.Fatalln(err.Error())
+	if batchSize == 0 {
+		batchSize = len(remaining)
+	}
+	for start := 0; start < len(remaining); start += batchSize {
+		end := min(start+batchSize, len(remaining))
+		batches = append(batches, remaining[start:end])
+	}
+
+	return batches, nil
+}
+
+// rolloutBatch applies the batch, then when runChecks is set gates it on health checks, rolling back unhealthy
+// routers if --rollback-on-failure is set. The connections the batch was applied over are reused for both
+// Returns an error if the rollout should not continue to the next batch
+func rolloutBatch(cfg *config.Config, batch []config.Router, devices map[string]*DeviceResult, runChecks bool) error {
+	previous, conns, err := applyBatch(cfg, batch, devices)
+	defer func(conns []*connection.SSHConnection) {
+		for _, ssh := range conns {
+			if ssh != nil {
+				_ = ssh.Close()
+			}
 		}
+	}(conns)
+	if err != nil {
+		return fmt.Errorf("batch failed: %w", err)
+	}
 
-		err = os.WriteFile(fmt.Sprintf("config.boot.%d", time.Now().Unix()), live, 0644)
-		if err != nil {
-			log.Fatalf("error saving backup of current config: %s", err.Error())
+	if !runChecks {
+		return nil
+	}
+
+	unhealthy := gateBatch(batch, conns, devices)
+	if len(unhealthy) == 0 {
+		return nil
+	}
+
+	if applyRollbackOnFailure {
+		for idx, router := range batch {
+			if _, ok := unhealthy[router.Name]; !ok {
+				continue
+			}
+			slog.Info("rolling back to the config from before this apply", "router", router.Name)
+			err = rollbackRouter(router, conns[idx], previous[idx])
+			if err != nil {
+				slog.Error("rollback failed", "router", router.Name, "error", err.Error())
+				devices[router.Name].Error = newResultError(withCode(codeRollback, fmt.Errorf("%w, and rollback failed: %w", unhealthy[router.Name], err)))
+				continue
+			}
+			devices[router.Name].Status = statusRolledBack
 		}
+	}
+
+	return fmt.Errorf("%d routers failed health checks", len(unhealthy))
+}
+
+// applyBatch applies the config to every router in the batch, up to --parallel at a time, recording the outcome in each router's result
+// Returns the live config from each router before the apply and the still open connection it was applied over,
+// in the same order as the batch. Connections for routers that failed are nil, the rest must be closed by the caller
+func applyBatch(cfg *config.Config, batch []config.Router, devices map[string]*DeviceResult) ([][]byte, []*connection.SSHConnection, error) {
+	var wg sync.WaitGroup
+	previous := make([][]byte, len(batch))
+	conns := make([]*connection.SSHConnection, len(batch))
+	errs := make([]error, len(batch))
+	slots := make(chan struct{}, applyParallel)
+
+	for idx, router := range batch {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(idx int, router config.Router) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			device := devices[router.Name]
+			device.start()
+			live, ssh, err := applyRouter(cfg, router, device)
+			if err != nil {
+				errs[idx] = fmt.Errorf("error applying config to %s: %w", router.Name, err)
+			}
+			previous[idx] = live
+			conns[idx] = ssh
+
+			// A full load writes the config even without changes, so only a skipped incremental apply is unchanged
+			status := statusChanged
+			if device.Mode == "incremental" && len(device.Changes) == 0 {
+				status = statusUnchanged
+			}
+			device.finish(status, err)
+		}(idx, router)
+	}
+	wg.Wait()
+
+	return previous, conns, errors.Join(errs...)
+}
+
+// gateBatch runs the health gate against every router in the batch concurrently, over the connections the batch
+// was applied over, recording the result for each
+// Returns the errors for any routers that did not become healthy, keyed by router name
+func gateBatch(batch []config.Router, conns []*connection.SSHConnection, devices map[string]*DeviceResult) map[string]error {
+	var wg sync.WaitGroup
+	reports := make([]*health.Report, len(batch))
+	errs := make([]error, len(batch))
+
+	for idx, router := range batch {
+		wg.Add(1)
+		go func(idx int, router config.Router) {
+			defer wg.Done()
+			reports[idx], errs[idx] = health.Gate(conns[idx], router, applyHealthTimeout, applyHealthInterval)
+		}(idx, router)
+	}
+	wg.Wait()
+
+	unhealthy := map[string]error{}
+	for idx, router := range batch {
+		device := devices[router.Name]
+		device.Health = reports[idx]
+		if errs[idx] != nil {
+			slog.Error("router failed health checks", "router", router.Name, "error", errs[idx].Error())
+			unhealthy[router.Name] = errs[idx]
+			device.Status = statusUnhealthy
+			device.Error = newResultError(withCode(codeHealth, errs[idx]))
+			continue
+		}
+		slog.Info("router is healthy", "router", router.Name)
+	}
+
+	return unhealthy
+}
+
+func routerNames(routers []config.Router) string {
+	names := make([]string, len(routers))
+	for idx, router := range routers {
+		names[idx] = router.Name
+	}
+	return strings.Join(names, ", ")
+}
+
+// applyRouter generates and applies the config for a single router, recording the mode and changes in its result
+// Returns the live config from before the apply, so it can be rolled back to if needed, along with the connection
+// it was applied over, which is left open for health checks and rollback. The connection is closed on error
+func applyRouter(cfg *config.Config, router config.Router, device *DeviceResult) ([]byte, *connection.SSHConnection, error) {
+	connDeets := router.Connection
+	ssh, err := connection.NewSSHConnection(connDeets.IP, connDeets.Port, connDeets.Username, connDeets.Password)
+	if err != nil {
+		return nil, nil, withCode(codeConnection, err)
+	}
+
+	live, err := pushRouterConfig(cfg, router, ssh, device)
+	if err != nil {
+		_ = ssh.Close()
+		return nil, nil, err
+	}
+
+	return live, ssh, nil
+}
+
+// pushRouterConfig generates the router's config and applies it over the connection, recording the mode and changes in its result
+// Returns the live config from before the apply
+func pushRouterConfig(cfg *config.Config, router config.Router, ssh *connection.SSHConnection, device *DeviceResult) ([]byte, error) {
+	live, err := ssh.FetchLiveConfig()
+	if err != nil {
+		return nil, withCode(codeConnection, err)
+	}
 
-		footer := util.LastNLines(string(live), 4)
-		withFooter := bytes.Join([][]byte{marshalled, []byte(footer)}, []byte("\n"))
+	marshalled, err := generateConfig(cfg, router, ssh, live)
+	if err != nil {
+		return nil, withCode(codeGenerate, err)
+	}
 
-		cfgPath := "/tmp/edgefig.cfg"
-		err = ssh.WriteFile(cfgPath, withFooter)
+	commands, renumbered, err := diffConfig(live, marshalled)
+	if err != nil {
+		return nil, withCode(codeGenerate, err)
+	}
+	device.Changes = commands
+	device.Renumbered = renumbered
+	if len(renumbered) > 0 {
+		slog.Warn("existing rules are moving to new rule numbers", "router", router.Name, "rules", len(renumbered))
+	}
+
+	store, err := newBackupStore()
+	if err != nil {
+		return nil, withCode(codeBackup, err)
+	}
+	_, err = store.Save(router.Name, live, backup.SourceApply)
+	if err != nil {
+		return nil, withCode(codeBackup, fmt.Errorf("error saving backup of current config: %w", err))
+	}
+
+	if applyIncremental {
+		device.Mode = "incremental"
+		switch {
+		case len(commands) == 0:
+			slog.Info("no changes to apply", "router", router.Name)
+			return live, nil
+		case len(commands) <= applyMaxIncremental:
+			slog.Info("applying incremental changes", "router", router.Name, "changes", len(commands))
+			return live, withCode(codeApply, ssh.ApplyCommands(commands))
+		default:
+			slog.Warn("too many changes for an incremental apply, falling back to a full load", "router", router.Name, "changes", len(commands), "max", applyMaxIncremental)
+		}
+	}
+	device.Mode = "full"
+
+	facts, err := ssh.Facts()
+	if err != nil {
+		return nil, withCode(codeFacts, fmt.Errorf("error gathering facts: %w", err))
+	}
+	if router.Model == "" {
+		router.Model = facts.DefaultConfigModel()
+	}
+	footer, err := configFooter(router, live)
+	if err != nil {
+		return nil, withCode(codeGenerate, err)
+	}
+	withFooter := append(marshalled, footer.Marshal()...)
+
+	return live, withCode(codeApply, pushConfig(ssh, withFooter))
+}
+
+// generateConfig discovers the router's interfaces and what it supports over the connection, then generates its config
+// Raw snippets are merged in, and any unmanaged sections of the router are copied over from the live config
+func generateConfig(cfg *config.Config, router config.Router, ssh *connection.SSHConnection, live []byte) ([]byte, error) {
+	availableInterfaces, err := ssh.GetAvailablePorts()
+	if err != nil {
+		return nil, fmt.Errorf("error getting available ports: %w", err)
+	}
+
+	facts, err := ssh.Facts()
+	if err != nil {
+		return nil, fmt.Errorf("error gathering facts: %w", err)
+	}
+
+	edgecfg, err := translate.ConfigToEdgeConfig(cfg, router, availableInterfaces)
+	if err != nil {
+		return nil, err
+	}
+
+	// The live config's footer lists the config components the router's firmware supports
+	platform := edgeconfig.Platform{Model: facts.Model}
+	if footer, err := edgeconfig.ParseFooter(live); err == nil {
+		platform.Components, _ = footer.ComponentVersions()
+	}
+	adapted, err := edgecfg.AdaptToPlatform(platform)
+	if err != nil {
+		return nil, err
+	}
+	for _, feature := range adapted {
+		slog.Warn("leaving out unsupported feature", "router", router.Name, "feature", feature, "firmware", facts.Firmware.String())
+	}
+
+	marshalled, err := edgeconfig.Marshal(edgecfg)
+	if err != nil {
+		return nil, err
+	}
+
+	marshalled, err = mergeRaw(router, marshalled)
+	if err != nil {
+		return nil, err
+	}
+
+	return preserveUnmanaged(router, live, marshalled)
+}
+
+// mergeRaw merges the router's raw config snippets into the generated config
+func mergeRaw(router config.Router, generated []byte) ([]byte, error) {
+	if len(router.Raw) == 0 {
+		return generated, nil
+	}
+
+	tree, err := edgeconfig.ParseTree(generated)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing generated config: %w", err)
+	}
+
+	for idx, snippet := range router.Raw {
+		err = tree.MergeRaw(snippet)
 		if err != nil {
-			log.Fatalln(err.Error())
+			return nil, fmt.Errorf("error merging raw config %d for %s: %w", idx+1, router.Name, err)
+		}
+	}
+
+	return tree.Marshal(), nil
+}
+
+// preserveUnmanaged grafts the router's unmanaged sections from the live config into the generated config
+func preserveUnmanaged(router config.Router, live, generated []byte) ([]byte, error) {
+	if len(router.Unmanaged) == 0 {
+		return generated, nil
+	}
+
+	liveTree, err := edgeconfig.ParseTree(live)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing live config: %w", err)
+	}
+	generatedTree, err := edgeconfig.ParseTree(generated)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing generated config: %w", err)
+	}
+
+	for _, path := range router.Unmanaged {
+		generatedTree.Graft(path, liveTree)
+	}
+
+	return generatedTree.Marshal(), nil
+}
+
+// diffConfig returns the set and delete commands needed to turn the live config into the desired config
+func diffConfig(live, desired []byte) ([]string, []string, error) {
+	liveTree, err := edgeconfig.ParseTree(live)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error parsing live config: %w", err)
+	}
+	desiredTree, err := edgeconfig.ParseTree(desired)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error parsing generated config: %w", err)
+	}
+
+	return edgeconfig.Diff(liveTree, desiredTree), edgeconfig.Renumbered(liveTree, desiredTree), nil
+}
+
+// configFooter returns the version footer to append to the generated config
+// The footer from the live config is preferred, falling back to the footer of the model's default config
+func configFooter(router config.Router, live []byte) (edgeconfig.Footer, error) {
+	footer, err := edgeconfig.ParseFooter(live)
+	if err != nil {
+		if router.Model == "" {
+			return edgeconfig.Footer{}, fmt.Errorf("could not read version footer from live config and no model is set to fall back to: %w", err)
 		}
+		slog.Warn("could not read version footer from live config, using the model's default", "router", router.Name, "model", router.Model, "error", err.Error())
 
-		err = ssh.ApplyConfig(cfgPath)
+		defaultConfig, err := defaultconfigs.Get(router.Model)
 		if err != nil {
-			log.Fatalln(err.Error())
+			return edgeconfig.Footer{}, err
 		}
+		footer, err = edgeconfig.ParseFooter(defaultConfig)
+		if err != nil {
+			return edgeconfig.Footer{}, fmt.Errorf("error reading version footer from default config for %s: %w", router.Model, err)
+		}
+	}
+
+	if untested := footer.UntestedComponents(); len(untested) > 0 {
+		slog.Warn("config component versions differ from those edgefig was tested against", "router", router.Name, "components", strings.Join(untested, ", "))
+	}
 
-		err = ssh.DeleteFile(cfgPath)
+	return footer, nil
+}
+
+// rollbackRouter pushes a previously saved config back to the router over the connection it was applied over
+// A new connection is only opened if that one has dropped, since a broken config may not accept new connections
+func rollbackRouter(router config.Router, ssh *connection.SSHConnection, previous []byte) error {
+	if len(previous) == 0 {
+		return fmt.Errorf("no previous config available for %s", router.Name)
+	}
+
+	if !ssh.Alive() {
+		slog.Warn("connection from the apply was lost, reconnecting to roll back", "router", router.Name)
+		connDeets := router.Connection
+		var err error
+		ssh, err = connection.NewSSHConnection(connDeets.IP, connDeets.Port, connDeets.Username, connDeets.Password)
 		if err != nil {
-			log.Fatalln(err.Error())
+			return err
 		}
-	},
+		defer func(ssh *connection.SSHConnection) {
+			_ = ssh.Close()
+		}(ssh)
+	}
+
+	return pushConfig(ssh, previous)
+}
+
+// pushConfig uploads a full config file to the router, then loads, commits, and saves it
+func pushConfig(ssh *connection.SSHConnection, contents []byte) error {
+	cfgPath := "/tmp/edgefig.cfg"
+	err := ssh.WriteFile(cfgPath, contents)
+	if err != nil {
+		return err
+	}
+
+	err = ssh.ApplyConfig(cfgPath)
+	if err != nil {
+		return err
+	}
+
+	return ssh.DeleteFile(cfgPath)
 }
 
 func init() {
+	applyCmd.Flags().BoolVar(&applyIncremental, "incremental", false, "apply only the set/delete commands needed to reach the desired config, instead of loading the full config")
+	applyCmd.Flags().IntVar(&applyMaxIncremental, "max-incremental-changes", 200, "fall back to a full load when an incremental apply would need more than this many commands")
+	applyCmd.Flags().IntVar(&applyBatchSize, "batch-size", 0, "number of routers in each batch, 0 applies all remaining routers in one batch")
+	applyCmd.Flags().IntVar(&applyParallel, "parallel", 1, "number of routers within a batch to apply to at the same time")
+	applyCmd.Flags().StringVar(&applyCanary, "canary", "", "name of a router to apply to first, before any other batches")
+	applyCmd.Flags().BoolVar(&applyHealthCheck, "health-check", false, "run health checks after applying, even when not rolling out in stages")
+	applyCmd.Flags().BoolVar(&applyRollbackOnFailure, "rollback-on-failure", false, "push the previous config back to any router that fails its health checks")
+	applyCmd.Flags().DurationVar(&applyHealthTimeout, "health-timeout", 5*time.Minute, "how long to wait for routers in a batch to become healthy before halting the rollout")
+	applyCmd.Flags().DurationVar(&applyHealthInterval, "health-interval", 15*time.Second, "how often to retry health checks while waiting for a batch to become healthy")
+
 	rootCmd.AddCommand(applyCmd)
 }
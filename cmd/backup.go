@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/cmmarslender/edgefig/internal/backup"
+	"github.com/cmmarslender/edgefig/internal/connection"
+	"github.com/cmmarslender/edgefig/pkg/config"
+)
+
+// backupCmd represents the backup command
+var backupCmd = &cobra.Command{
+	Use:   "backup",
+	Short: "Fetches the live config from all devices and saves it to the backup store",
+	Long: `Fetches the live config from all devices and saves it to the backup store
+
+Run this on a schedule with --backup-store git to keep a full history of changes made to the routers,
+including changes made by hand outside of edgefig.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		result := newResult("backup")
+		_, routers, err := loadSelectedRouters()
+		if err != nil {
+			failResult(result, codeConfig, err)
+		}
+
+		store, err := newBackupStore()
+		if err != nil {
+			failResult(result, codeBackup, err)
+		}
+
+		for _, router := range routers {
+			device := result.device(router.Name)
+			device.start()
+			saved, err := backupRouter(router, store)
+			if err == nil {
+				device.Backups = []backup.Backup{saved}
+			}
+			device.finish(statusOK, err)
+		}
+
+		finishResult(result, func(result *Result) {
+			for _, device := range result.Devices {
+				if device.Error != nil {
+					fmt.Printf("%s: ERROR %s\n", device.Name, device.Error.Message)
+					continue
+				}
+				for _, saved := range device.Backups {
+					fmt.Printf("%s: saved backup from %s (%s)\n", device.Name, saved.Time().Format(time.RFC3339), saved.Location)
+				}
+			}
+		})
+	},
+}
+
+// backupRouter fetches the router's live config and saves it to the store
+func backupRouter(router config.Router, store backup.Store) (backup.Backup, error) {
+	connDeets := router.Connection
+	ssh, err := connection.NewSSHConnection(connDeets.IP, connDeets.Port, connDeets.Username, connDeets.Password)
+	if err != nil {
+		return backup.Backup{}, withCode(codeConnection, err)
+	}
+	defer func(ssh *connection.SSHConnection) {
+		_ = ssh.Close()
+	}(ssh)
+
+	live, err := ssh.FetchLiveConfig()
+	if err != nil {
+		return backup.Backup{}, withCode(codeConnection, fmt.Errorf("error fetching live config: %w", err))
+	}
+
+	saved, err := store.Save(router.Name, live, backup.SourceBackup)
+	if err != nil {
+		return backup.Backup{}, withCode(codeBackup, fmt.Errorf("error saving backup: %w", err))
+	}
+	slog.Info("saved backup", "router", router.Name, "timestamp", saved.Timestamp, "location", saved.Location)
+
+	return saved, nil
+}
+
+func init() {
+	rootCmd.AddCommand(backupCmd)
+}
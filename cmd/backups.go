@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// backupsCmd represents the backups command
+var backupsCmd = &cobra.Command{
+	Use:   "backups",
+	Short: "Inspect saved router config backups",
+}
+
+// backupsListCmd represents the backups list command
+var backupsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Lists the saved backups for all devices",
+	Run: func(cmd *cobra.Command, args []string) {
+		result := newResult("backups list")
+		_, routers, err := loadSelectedRouters()
+		if err != nil {
+			failResult(result, codeConfig, err)
+		}
+
+		store, err := newBackupStore()
+		if err != nil {
+			failResult(result, codeBackup, err)
+		}
+
+		for _, router := range routers {
+			device := result.device(router.Name)
+			device.start()
+			device.Backups, err = store.List(router.Name)
+			device.finish(statusOK, withCode(codeBackup, err))
+		}
+
+		finishResult(result, func(result *Result) {
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			_, _ = fmt.Fprintln(w, "ROUTER\tTIMESTAMP\tTIME\tLOCATION")
+			for _, device := range result.Devices {
+				if device.Error != nil {
+					_, _ = fmt.Fprintf(w, "%s\t-\t-\tERROR %s\n", device.Name, device.Error.Message)
+					continue
+				}
+				for _, b := range device.Backups {
+					_, _ = fmt.Fprintf(w, "%s\t%d\t%s\t%s\n", b.Router, b.Timestamp, b.Time().Format(time.RFC3339), b.Location)
+				}
+			}
+			_ = w.Flush()
+		})
+	},
+}
+
+func init() {
+	backupsCmd.AddCommand(backupsListCmd)
+	rootCmd.AddCommand(backupsCmd)
+}
@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/spf13/cobra"
+
+	"github.com/cmmarslender/edgefig/internal/backup"
+	"github.com/cmmarslender/edgefig/pkg/config"
+)
+
+// driftCmd represents the drift command
+var driftCmd = &cobra.Command{
+	Use:   "drift",
+	Short: "Checks whether each device's live config has drifted from the configuration",
+	Long: `Checks whether each device's live config has drifted from the configuration
+
+Drift is anything on the router that differs from the generated config, such as changes made by hand.
+The set/delete commands that would bring the router back in line are listed for each drifted device,
+and the command exits non-zero if any device has drifted, so it can be run on a schedule or in CI.
+The live config of every device is saved to the backup store, so drift is recorded in its history.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		result := newResult("drift")
+		cfg, routers, err := loadSelectedRouters()
+		if err != nil {
+			failResult(result, codeConfig, err)
+		}
+
+		store, err := newBackupStore()
+		if err != nil {
+			failResult(result, codeBackup, err)
+		}
+
+		for _, router := range routers {
+			device := result.device(router.Name)
+			device.start()
+			device.Changes, device.Backups, err = driftRouter(cfg, router, store)
+			if err != nil {
+				device.finish(statusFailed, err)
+				continue
+			}
+			if len(device.Changes) > 0 {
+				device.finish(statusDrifted, withCode(codeDrift, fmt.Errorf("live config differs from the configuration by %d commands", len(device.Changes))))
+				continue
+			}
+			device.finish(statusOK, nil)
+		}
+
+		finishResult(result, func(result *Result) {
+			for _, device := range result.Devices {
+				switch {
+				case device.Status == statusDrifted:
+					fmt.Printf("%s: drifted, %d commands to bring it back in line\n", device.Name, len(device.Changes))
+					for _, command := range device.Changes {
+						fmt.Printf("    %s\n", command)
+					}
+				case device.Error != nil:
+					fmt.Printf("%s: ERROR %s\n", device.Name, device.Error.Message)
+				default:
+					fmt.Printf("%s: no drift\n", device.Name)
+				}
+			}
+		})
+	},
+}
+
+// driftRouter returns the commands that would bring the router's live config back in line with the configuration
+// The live config is saved to the backup store, even if the config for the router can't be generated
+func driftRouter(cfg *config.Config, router config.Router, store backup.Store) ([]string, []backup.Backup, error) {
+	live, commands, _, err := diffLive(cfg, router)
+	if live == nil {
+		return nil, nil, err
+	}
+
+	saved, saveErr := store.Save(router.Name, live, backup.SourceDrift)
+	if saveErr != nil {
+		return nil, nil, withCode(codeBackup, fmt.Errorf("error saving backup: %w", saveErr))
+	}
+	slog.Info("saved backup", "router", router.Name, "timestamp", saved.Timestamp, "location", saved.Location)
+
+	return commands, []backup.Backup{saved}, err
+}
+
+func init() {
+	rootCmd.AddCommand(driftCmd)
+}
@@ -1,45 +1,130 @@
 package cmd
 
 import (
-	"log"
+	"encoding/json"
+	"fmt"
 	"os"
+	"strings"
 
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
 
 	"github.com/cmmarslender/edgefig/pkg/config"
 	"github.com/cmmarslender/edgefig/pkg/edgeconfig"
 	"github.com/cmmarslender/edgefig/pkg/translate"
 )
 
+var dumpConfigFormat string
+
 // dumpConfigCmd represents the dumpConfig command
 var dumpConfigCmd = &cobra.Command{
 	Use:   "dump-config",
 	Short: "Dumps the generated configs to files",
 	Run: func(cmd *cobra.Command, args []string) {
-		cfg, err := config.LoadConfig(viper.GetString("config"))
+		result := newResult("dump-config")
+		cfg, routers, err := loadSelectedRouters()
 		if err != nil {
-			log.Fatalln(err.Error())
+			failResult(result, codeConfig, err)
 		}
 
-		// @TODO dynamic or CLI configurable number of interfaces?
-		edgecfg, err := translate.ConfigToEdgeConfig(cfg, map[string]struct{}{})
-		if err != nil {
-			log.Fatalln(err.Error())
+		for _, router := range routers {
+			device := result.device(router.Name)
+			device.start()
+			device.File, err = dumpRouterConfig(cfg, router)
+			device.finish(statusOK, err)
 		}
 
-		marshalled, err := edgeconfig.Marshal(edgecfg)
-		if err != nil {
-			log.Fatalln(err.Error())
-		}
+		finishResult(result, func(result *Result) {
+			for _, device := range result.Devices {
+				if device.Error != nil {
+					fmt.Printf("%s: ERROR %s\n", device.Name, device.Error.Message)
+					continue
+				}
+				fmt.Printf("%s: wrote %s\n", device.Name, device.File)
+			}
+		})
+	},
+}
 
-		err = os.WriteFile("config-out", marshalled, 0644)
-		if err != nil {
-			log.Fatalln(err.Error())
+// dumpRouterConfig generates the router's config in the --format format and writes it to a file, returning the file name
+func dumpRouterConfig(cfg *config.Config, router config.Router) (string, error) {
+	interfaces, err := offlineInterfaces(router)
+	if err != nil {
+		return "", withCode(codeConfig, err)
+	}
+
+	edgecfg, err := translate.ConfigToEdgeConfig(cfg, router, interfaces)
+	if err != nil {
+		return "", withCode(codeGenerate, err)
+	}
+
+	var marshalled []byte
+	switch dumpConfigFormat {
+	case "boot":
+		marshalled, err = edgeconfig.Marshal(edgecfg)
+		if err == nil {
+			marshalled, err = mergeRaw(router, marshalled)
 		}
-	},
+	case "set":
+		marshalled, err = marshalSet(router, edgecfg)
+	case "json":
+		marshalled, err = marshalJSON(router, edgecfg)
+	default:
+		err = fmt.Errorf("unknown format %s, expected boot, set, or json", dumpConfigFormat)
+	}
+	if err != nil {
+		return "", withCode(codeGenerate, err)
+	}
+
+	file := fmt.Sprintf("config-out.%s", router.Name)
+	err = os.WriteFile(file, marshalled, 0644)
+	if err != nil {
+		return "", fmt.Errorf("error writing %s: %w", file, err)
+	}
+
+	return file, nil
+}
+
+// marshalSet generates the router's config, including raw snippets, as set commands
+func marshalSet(router config.Router, edgecfg *edgeconfig.Router) ([]byte, error) {
+	tree, err := generatedTree(router, edgecfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(strings.Join(tree.SetCommands(), "\n") + "\n"), nil
+}
+
+// marshalJSON generates the router's config, including raw snippets, as JSON keyed by config.boot node names
+func marshalJSON(router config.Router, edgecfg *edgeconfig.Router) ([]byte, error) {
+	tree, err := generatedTree(router, edgecfg)
+	if err != nil {
+		return nil, err
+	}
+
+	marshalled, err := json.MarshalIndent(tree.Map(), "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return append(marshalled, '\n'), nil
+}
+
+// generatedTree generates the router's config, including raw snippets, as a config tree
+func generatedTree(router config.Router, edgecfg *edgeconfig.Router) (*edgeconfig.Node, error) {
+	marshalled, err := edgeconfig.Marshal(edgecfg)
+	if err != nil {
+		return nil, err
+	}
+	marshalled, err = mergeRaw(router, marshalled)
+	if err != nil {
+		return nil, err
+	}
+
+	return edgeconfig.ParseTree(marshalled)
 }
 
 func init() {
+	dumpConfigCmd.Flags().StringVar(&dumpConfigFormat, "format", "boot", "output format: boot (config.boot syntax), set (EdgeOS set commands), or json")
+
 	rootCmd.AddCommand(dumpConfigCmd)
 }
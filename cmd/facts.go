@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/cmmarslender/edgefig/internal/connection"
+	"github.com/cmmarslender/edgefig/pkg/config"
+)
+
+// factsCmd represents the facts command
+var factsCmd = &cobra.Command{
+	Use:   "facts",
+	Short: "Prints facts (model, firmware, serial, uptime) about all devices as JSON",
+	Long: `Prints facts (model, firmware, serial, uptime) about all devices as JSON
+
+With the default text output, the facts are printed as a map of router name to facts.
+With --output json, they are included in the result document for each device instead.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		result := newResult("facts")
+		_, routers, err := loadSelectedRouters()
+		if err != nil {
+			failResult(result, codeConfig, err)
+		}
+
+		for _, router := range routers {
+			device := result.device(router.Name)
+			device.start()
+			device.Facts, err = gatherFacts(router)
+			device.finish(statusOK, err)
+		}
+
+		finishResult(result, func(result *Result) {
+			type routerFacts struct {
+				*connection.Facts
+				Error string `json:"error,omitempty"`
+			}
+
+			allFacts := map[string]routerFacts{}
+			for _, device := range result.Devices {
+				facts := routerFacts{Facts: device.Facts}
+				if device.Error != nil {
+					facts.Error = device.Error.Message
+				}
+				allFacts[device.Name] = facts
+			}
+
+			out, err := json.MarshalIndent(allFacts, "", "  ")
+			if err != nil {
+				slog.Error("error marshalling facts", "error", err.Error())
+				os.Exit(1)
+			}
+			fmt.Println(string(out))
+		})
+	},
+}
+
+// gatherFacts connects to the router and gathers its facts
+func gatherFacts(router config.Router) (*connection.Facts, error) {
+	connDeets := router.Connection
+	ssh, err := connection.NewSSHConnection(connDeets.IP, connDeets.Port, connDeets.Username, connDeets.Password)
+	if err != nil {
+		return nil, withCode(codeConnection, err)
+	}
+	defer func(ssh *connection.SSHConnection) {
+		_ = ssh.Close()
+	}(ssh)
+
+	facts, err := ssh.Facts()
+	return facts, withCode(codeFacts, err)
+}
+
+func init() {
+	rootCmd.AddCommand(factsCmd)
+}
@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/cmmarslender/edgefig/internal/health"
+)
+
+// healthCmd represents the health command
+var healthCmd = &cobra.Command{
+	Use:   "health",
+	Short: "Runs health checks against all devices and reports the results",
+	Run: func(cmd *cobra.Command, args []string) {
+		result := newResult("health")
+		_, routers, err := loadSelectedRouters()
+		if err != nil {
+			failResult(result, codeConfig, err)
+		}
+
+		for _, router := range routers {
+			device := result.device(router.Name)
+			device.start()
+			device.Health, err = health.Connect(router)
+			if err != nil {
+				device.finish(statusFailed, withCode(codeConnection, err))
+				continue
+			}
+			if !device.Health.Healthy() {
+				device.finish(statusUnhealthy, withCode(codeHealth, device.Health.Error()))
+				continue
+			}
+			device.finish(statusOK, nil)
+		}
+
+		finishResult(result, func(result *Result) {
+			for _, device := range result.Devices {
+				switch {
+				case device.Health == nil:
+					fmt.Printf("%s: ERROR %s\n", device.Name, device.Error.Message)
+				case device.Health.Healthy():
+					fmt.Printf("%s: healthy\n", device.Name)
+				default:
+					fmt.Printf("%s: UNHEALTHY\n", device.Name)
+					for _, failure := range device.Health.Failures {
+						fmt.Printf("    %s\n", failure)
+					}
+				}
+			}
+		})
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(healthCmd)
+}
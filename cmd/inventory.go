@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"fmt"
+	"net/netip"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+// inventoryCmd represents the inventory command
+var inventoryCmd = &cobra.Command{
+	Use:   "inventory",
+	Short: "Lists the devices selected by --limit along with their tags and connection targets",
+	Run: func(cmd *cobra.Command, args []string) {
+		result := newResult("inventory")
+		_, routers, err := loadSelectedRouters()
+		if err != nil {
+			failResult(result, codeConfig, err)
+		}
+
+		for _, router := range routers {
+			device := result.device(router.Name)
+			device.start()
+			device.Inventory = &InventoryEntry{
+				Tags:   router.Tags,
+				Target: netip.AddrPortFrom(router.IP, router.Port).String(),
+				User:   router.Username,
+			}
+			device.finish(statusOK, nil)
+		}
+
+		finishResult(result, func(result *Result) {
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			_, _ = fmt.Fprintln(w, "NAME\tTYPE\tTAGS\tTARGET\tUSER")
+			for _, device := range result.Devices {
+				_, _ = fmt.Fprintf(w, "%s\trouter\t%s\t%s\t%s\n",
+					device.Name,
+					strings.Join(device.Inventory.Tags, ","),
+					device.Inventory.Target,
+					device.Inventory.User,
+				)
+			}
+			_ = w.Flush()
+		})
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(inventoryCmd)
+}
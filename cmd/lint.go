@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/cmmarslender/edgefig/pkg/lint"
+)
+
+var lintFailOn string
+
+// lintCmd represents the lint command
+var lintCmd = &cobra.Command{
+	Use:   "lint",
+	Short: "Checks firewall rules for shadowed, duplicate and unreachable rules, empty groups, and unused zones",
+	Run: func(cmd *cobra.Command, args []string) {
+		result := newResult("lint")
+		switch lintFailOn {
+		case string(lint.SeverityError), string(lint.SeverityWarning), "none":
+		default:
+			failResult(result, codeConfig, fmt.Errorf("unknown --fail-on %s, expected error, warning, or none", lintFailOn))
+		}
+
+		cfg, routers, err := loadSelectedRouters()
+		if err != nil {
+			failResult(result, codeConfig, err)
+		}
+
+		for _, router := range routers {
+			device := result.device(router.Name)
+			device.start()
+			device.Findings, err = lint.Firewall(cfg, router)
+			if err != nil {
+				device.finish(statusFailed, withCode(codeConfig, err))
+				continue
+			}
+
+			failing := 0
+			for _, finding := range device.Findings {
+				if lintFailOn != "none" && finding.Severity.AtLeast(lint.Severity(lintFailOn)) {
+					failing++
+				}
+			}
+			if failing > 0 {
+				err = withCode(codeLint, fmt.Errorf("%d findings at %s severity or above", failing, lintFailOn))
+			}
+			device.finish(statusOK, err)
+		}
+
+		finishResult(result, func(result *Result) {
+			for _, device := range result.Devices {
+				if len(device.Findings) == 0 && device.Error == nil {
+					fmt.Printf("%s: no findings\n", device.Name)
+					continue
+				}
+				fmt.Printf("%s: %d findings\n", device.Name, len(device.Findings))
+				for _, finding := range device.Findings {
+					fmt.Printf("    %s\n", finding)
+				}
+				if device.Error != nil {
+					fmt.Printf("%s: ERROR %s\n", device.Name, device.Error.Message)
+				}
+			}
+		})
+	},
+}
+
+func init() {
+	lintCmd.Flags().StringVar(&lintFailOn, "fail-on", string(lint.SeverityError), "exit non-zero when there are findings of this severity or above: error, warning, or none")
+
+	rootCmd.AddCommand(lintCmd)
+}
@@ -0,0 +1,227 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/cmmarslender/edgefig/internal/backup"
+	"github.com/cmmarslender/edgefig/internal/connection"
+	"github.com/cmmarslender/edgefig/internal/health"
+	"github.com/cmmarslender/edgefig/pkg/lint"
+	"github.com/cmmarslender/edgefig/pkg/simulate"
+)
+
+// Error codes included in results, so tooling can react to failures without parsing messages
+const (
+	codeConfig     = "config_error"
+	codeConnection = "connection_error"
+	codeGenerate   = "generate_error"
+	codeBackup     = "backup_error"
+	codeApply      = "apply_error"
+	codeHealth     = "health_check_failed"
+	codeRollback   = "rollback_failed"
+	codeFacts      = "facts_error"
+	codeSimulate   = "simulate_error"
+	codeAssertion  = "assertion_failed"
+	codeLint       = "lint_failed"
+	codeDrift      = "drift_detected"
+	codeUnknown    = "error"
+)
+
+// Device statuses included in results
+const (
+	statusOK         = "ok"
+	statusChanged    = "changed"
+	statusUnchanged  = "unchanged"
+	statusFailed     = "failed"
+	statusUnhealthy  = "unhealthy"
+	statusRolledBack = "rolled_back"
+	statusDrifted    = "drifted"
+	statusSkipped    = "skipped"
+)
+
+// codedError attaches one of the error codes above to an error
+type codedError struct {
+	code string
+	err  error
+}
+
+func (e *codedError) Error() string {
+	return e.err.Error()
+}
+
+func (e *codedError) Unwrap() error {
+	return e.err
+}
+
+// withCode attaches an error code to the error, unless it is nil or already has one
+func withCode(code string, err error) error {
+	if err == nil {
+		return nil
+	}
+	var coded *codedError
+	if errors.As(err, &coded) {
+		return err
+	}
+	return &codedError{code: code, err: err}
+}
+
+// ResultError is an error in a result, with a stable code and a human readable message
+type ResultError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func newResultError(err error) *ResultError {
+	if err == nil {
+		return nil
+	}
+	code := codeUnknown
+	var coded *codedError
+	if errors.As(err, &coded) {
+		code = coded.code
+	}
+	return &ResultError{Code: code, Message: err.Error()}
+}
+
+// DeviceResult is the outcome of a command for a single router
+type DeviceResult struct {
+	Name        string              `json:"name"`
+	Status      string              `json:"status"`
+	Started     time.Time           `json:"started"`
+	DurationMS  int64               `json:"duration_ms"`
+	Error       *ResultError        `json:"error,omitempty"`
+	Mode        string              `json:"mode,omitempty"`
+	Changes     []string            `json:"changes,omitempty"`
+	Renumbered  []string            `json:"renumbered,omitempty"`
+	Facts       *connection.Facts   `json:"facts,omitempty"`
+	Health      *health.Report      `json:"health,omitempty"`
+	Simulations []*SimulationResult `json:"simulations,omitempty"`
+	Findings    []lint.Finding      `json:"findings,omitempty"`
+	Backups     []backup.Backup     `json:"backups,omitempty"`
+	Inventory   *InventoryEntry     `json:"inventory,omitempty"`
+	File        string              `json:"file,omitempty"`
+	Rendered    string              `json:"rendered,omitempty"`
+}
+
+// InventoryEntry is how edgefig reaches a router, along with its tags
+type InventoryEntry struct {
+	Tags   []string `json:"tags,omitempty"`
+	Target string   `json:"target"`
+	User   string   `json:"user"`
+}
+
+// SimulationResult is a simulated packet and its verdict, along with whether it matched the expected action when run as an assertion
+type SimulationResult struct {
+	Name    string            `json:"name,omitempty"`
+	Expect  string            `json:"expect,omitempty"`
+	Passed  *bool             `json:"passed,omitempty"`
+	Verdict *simulate.Verdict `json:"verdict"`
+}
+
+// start marks the device as started now
+func (d *DeviceResult) start() {
+	d.Started = time.Now()
+}
+
+// finish records the device's status and how long it took
+// An error alongside a successful status (ok, changed, unchanged) marks the device as failed instead
+func (d *DeviceResult) finish(status string, err error) {
+	d.DurationMS = time.Since(d.Started).Milliseconds()
+	d.Status = status
+	if err == nil {
+		return
+	}
+	d.Error = newResultError(err)
+	switch status {
+	case statusOK, statusChanged, statusUnchanged:
+		d.Status = statusFailed
+	}
+}
+
+// Result is the machine readable result of a command, printed with --output json
+type Result struct {
+	Command    string          `json:"command"`
+	Success    bool            `json:"success"`
+	Started    time.Time       `json:"started"`
+	DurationMS int64           `json:"duration_ms"`
+	Error      *ResultError    `json:"error,omitempty"`
+	Devices    []*DeviceResult `json:"devices"`
+}
+
+func newResult(command string) *Result {
+	return &Result{Command: command, Success: true, Started: time.Now(), Devices: []*DeviceResult{}}
+}
+
+// device adds a result for the router, in the order devices are added
+func (r *Result) device(name string) *DeviceResult {
+	device := &DeviceResult{Name: name, Status: statusSkipped}
+	r.Devices = append(r.Devices, device)
+	return device
+}
+
+// jsonOutput returns whether results should be printed as JSON
+func jsonOutput() bool {
+	return viper.GetString("output") == "json"
+}
+
+// finishResult prints the result, using printText when not outputting JSON, then exits non-zero if the command failed
+// Any device that failed fails the whole command
+func finishResult(result *Result, printText func(result *Result)) {
+	result.DurationMS = time.Since(result.Started).Milliseconds()
+	for _, device := range result.Devices {
+		if device.Error != nil {
+			result.Success = false
+		}
+	}
+
+	if jsonOutput() {
+		out, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			slog.Error("error marshalling result", "error", err.Error())
+			os.Exit(1)
+		}
+		fmt.Println(string(out))
+	} else {
+		if result.Error != nil {
+			slog.Error(result.Error.Message, "code", result.Error.Code)
+		}
+		printText(result)
+	}
+
+	if !result.Success {
+		os.Exit(1)
+	}
+}
+
+// failResult fails the whole command with the error, before any device was processed
+func failResult(result *Result, code string, err error) {
+	result.Success = false
+	result.Error = newResultError(withCode(code, err))
+	finishResult(result, func(*Result) {})
+}
+
+// setupLogging sends logs to stderr at the configured level, so stdout only contains command results
+func setupLogging() error {
+	var level slog.Level
+	err := level.UnmarshalText([]byte(viper.GetString("log-level")))
+	if err != nil {
+		return fmt.Errorf("invalid log level %s: %w", viper.GetString("log-level"), err)
+	}
+
+	switch viper.GetString("output") {
+	case "text", "json":
+	default:
+		return fmt.Errorf("unknown output format %s, expected text or json", viper.GetString("output"))
+	}
+
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level})))
+
+	return nil
+}
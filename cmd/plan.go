@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/spf13/cobra"
+
+	"github.com/cmmarslender/edgefig/internal/connection"
+	"github.com/cmmarslender/edgefig/pkg/config"
+)
+
+// planCmd represents the plan command
+var planCmd = &cobra.Command{
+	Use:   "plan",
+	Short: "Shows the set/delete commands needed to bring each device in line with the configuration",
+	Run: func(cmd *cobra.Command, args []string) {
+		result := newResult("plan")
+		cfg, routers, err := loadSelectedRouters()
+		if err != nil {
+			failResult(result, codeConfig, err)
+		}
+
+		for _, router := range routers {
+			device := result.device(router.Name)
+			device.start()
+			device.Changes, device.Renumbered, err = planRouter(cfg, router)
+			if err != nil {
+				slog.Error("error planning changes", "router", router.Name, "error", err.Error())
+			}
+			status := statusUnchanged
+			if len(device.Changes) > 0 {
+				status = statusChanged
+			}
+			device.finish(status, err)
+		}
+
+		finishResult(result, func(result *Result) {
+			for _, device := range result.Devices {
+				switch {
+				case device.Error != nil:
+					fmt.Printf("%s: ERROR %s\n", device.Name, device.Error.Message)
+				case len(device.Changes) == 0:
+					fmt.Printf("%s: no changes\n", device.Name)
+				default:
+					fmt.Printf("%s: %d changes\n", device.Name, len(device.Changes))
+					if len(device.Renumbered) > 0 {
+						fmt.Printf("    WARNING: %d existing rules move to a new rule number, set number: on them to keep their current numbers\n", len(device.Renumbered))
+						for _, move := range device.Renumbered {
+							fmt.Printf("        %s\n", move)
+						}
+					}
+					for _, command := range device.Changes {
+						fmt.Printf("    %s\n", command)
+					}
+				}
+			}
+		})
+	},
+}
+
+// planRouter generates the config for the router and diffs it against the live config
+// Returns the commands to run, along with the existing rules they move to a new rule number
+func planRouter(cfg *config.Config, router config.Router) ([]string, []string, error) {
+	_, commands, renumbered, err := diffLive(cfg, router)
+	return commands, renumbered, err
+}
+
+// diffLive fetches the router's live config and diffs it against the generated config
+// Returns the live config along with the set and delete commands that bring it in line with the generated config,
+// and the rules those commands move to a new rule number
+func diffLive(cfg *config.Config, router config.Router) ([]byte, []string, []string, error) {
+	connDeets := router.Connection
+	ssh, err := connection.NewSSHConnection(connDeets.IP, connDeets.Port, connDeets.Username, connDeets.Password)
+	if err != nil {
+		return nil, nil, nil, withCode(codeConnection, err)
+	}
+	defer func(ssh *connection.SSHConnection) {
+		_ = ssh.Close()
+	}(ssh)
+
+	live, err := ssh.FetchLiveConfig()
+	if err != nil {
+		return nil, nil, nil, withCode(codeConnection, err)
+	}
+
+	marshalled, err := generateConfig(cfg, router, ssh, live)
+	if err != nil {
+		return live, nil, nil, withCode(codeGenerate, err)
+	}
+
+	commands, renumbered, err := diffConfig(live, marshalled)
+	return live, commands, renumbered, withCode(codeGenerate, err)
+}
+
+func init() {
+	rootCmd.AddCommand(planCmd)
+}
@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+
+	"github.com/cmmarslender/edgefig/pkg/config"
+)
+
+// renderCmd represents the render command
+var renderCmd = &cobra.Command{
+	Use:   "render <router>",
+	Short: "Prints the fully resolved config for a router, after profiles have been applied",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		result := newResult("render")
+		cfg, err := config.LoadConfig(viper.GetString("config"))
+		if err != nil {
+			failResult(result, codeConfig, err)
+		}
+
+		router, err := cfg.GetRouterByName(args[0])
+		if err != nil {
+			failResult(result, codeConfig, err)
+		}
+
+		device := result.device(router.Name)
+		device.start()
+		device.Rendered, err = renderRouter(router)
+		device.finish(statusOK, withCode(codeGenerate, err))
+
+		finishResult(result, func(result *Result) {
+			for _, device := range result.Devices {
+				if device.Error != nil {
+					fmt.Printf("%s: ERROR %s\n", device.Name, device.Error.Message)
+					continue
+				}
+				fmt.Print(device.Rendered)
+			}
+		})
+	},
+}
+
+// redacted replaces passwords in rendered output, so render can be shared without leaking credentials
+const redacted = "<redacted>"
+
+// renderRouter returns the router's resolved config as yaml, leaving out values that aren't set and redacting passwords
+func renderRouter(router config.Router) (string, error) {
+	var node yaml.Node
+	err := node.Encode(router)
+	if err != nil {
+		return "", err
+	}
+	pruneEmpty(&node)
+	redactPasswords(&node)
+
+	rendered, err := yaml.Marshal(&node)
+	if err != nil {
+		return "", err
+	}
+
+	return string(rendered), nil
+}
+
+// pruneEmpty removes unset values (null, empty strings, and empty maps and lists) from an encoded yaml node so only configured values are shown
+// false and 0 are kept, since they can be set on purpose
+// Returns true if the node itself is empty and should be removed by its parent
+func pruneEmpty(node *yaml.Node) bool {
+	switch node.Kind {
+	case yaml.MappingNode:
+		var content []*yaml.Node
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if pruneEmpty(node.Content[i+1]) {
+				continue
+			}
+			content = append(content, node.Content[i], node.Content[i+1])
+		}
+		node.Content = content
+		return len(content) == 0
+	case yaml.SequenceNode:
+		var content []*yaml.Node
+		for _, child := range node.Content {
+			if !pruneEmpty(child) {
+				content = append(content, child)
+			}
+		}
+		node.Content = content
+		return len(content) == 0
+	case yaml.ScalarNode:
+		return node.Tag == "!!null" || node.Value == ""
+	}
+
+	return false
+}
+
+// redactPasswords replaces the value of every password key in an encoded yaml node
+func redactPasswords(node *yaml.Node) {
+	if node.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			if key.Value == "password" && value.Kind == yaml.ScalarNode {
+				value.Value = redacted
+				value.Tag = "!!str"
+				value.Style = 0
+			}
+		}
+	}
+	for _, child := range node.Content {
+		redactPasswords(child)
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(renderCmd)
+}
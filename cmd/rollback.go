@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/cmmarslender/edgefig/internal/backup"
+	"github.com/cmmarslender/edgefig/internal/connection"
+	"github.com/cmmarslender/edgefig/pkg/config"
+)
+
+var rollbackTo int64
+
+// rollbackCmd represents the rollback command
+var rollbackCmd = &cobra.Command{
+	Use:   "rollback <router>",
+	Short: "Pushes a saved backup back to a router",
+	Long: `Pushes a saved backup back to a router, using the same load, commit, and save process as apply
+
+By default the newest backup is restored, which is the config from before the most recent apply.
+Use --to with a timestamp from "edgefig backups list" to restore an older backup.
+The current config is backed up before the rollback, so a rollback can itself be rolled back.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		result := newResult("rollback")
+		cfg, err := config.LoadConfig(viper.GetString("config"))
+		if err != nil {
+			failResult(result, codeConfig, err)
+		}
+
+		router, err := cfg.GetRouterByName(args[0])
+		if err != nil {
+			failResult(result, codeConfig, err)
+		}
+
+		store, err := newBackupStore()
+		if err != nil {
+			failResult(result, codeBackup, err)
+		}
+
+		device := result.device(router.Name)
+		device.start()
+		restored, err := restoreBackup(router, store)
+		if err == nil {
+			device.Backups = []backup.Backup{restored}
+		}
+		device.finish(statusChanged, err)
+
+		finishResult(result, func(result *Result) {
+			for _, device := range result.Devices {
+				if device.Error != nil {
+					fmt.Printf("%s: ERROR %s\n", device.Name, device.Error.Message)
+					continue
+				}
+				for _, restored := range device.Backups {
+					fmt.Printf("%s: rolled back to backup from %s\n", device.Name, restored.Time().Format(time.RFC3339))
+				}
+			}
+		})
+	},
+}
+
+// restoreBackup backs up the router's current config, then pushes the backup selected by --to (or the newest one)
+func restoreBackup(router config.Router, store backup.Store) (backup.Backup, error) {
+	timestamp := rollbackTo
+	if timestamp == 0 {
+		latest, err := backup.Latest(store, router.Name)
+		if err != nil {
+			return backup.Backup{}, withCode(codeBackup, err)
+		}
+		timestamp = latest.Timestamp
+	}
+
+	contents, err := store.Load(router.Name, timestamp)
+	if err != nil {
+		return backup.Backup{}, withCode(codeBackup, err)
+	}
+
+	connDeets := router.Connection
+	ssh, err := connection.NewSSHConnection(connDeets.IP, connDeets.Port, connDeets.Username, connDeets.Password)
+	if err != nil {
+		return backup.Backup{}, withCode(codeConnection, err)
+	}
+	defer func(ssh *connection.SSHConnection) {
+		_ = ssh.Close()
+	}(ssh)
+
+	live, err := ssh.FetchLiveConfig()
+	if err != nil {
+		return backup.Backup{}, withCode(codeConnection, err)
+	}
+	_, err = store.Save(router.Name, live, backup.SourceRollback)
+	if err != nil {
+		return backup.Backup{}, withCode(codeBackup, fmt.Errorf("error saving backup of current config: %w", err))
+	}
+
+	slog.Info("rolling back to backup", "router", router.Name, "backup", time.Unix(timestamp, 0).Format(time.RFC3339))
+	err = pushConfig(ssh, contents)
+	if err != nil {
+		return backup.Backup{}, withCode(codeRollback, err)
+	}
+
+	return backup.Backup{Router: router.Name, Timestamp: timestamp}, nil
+}
+
+func init() {
+	rollbackCmd.Flags().Int64Var(&rollbackTo, "to", 0, "unix timestamp of the backup to restore (defaults to the newest backup)")
+
+	rootCmd.AddCommand(rollbackCmd)
+}
@@ -2,11 +2,17 @@ package cmd
 
 import (
 	"fmt"
+	"log/slog"
 	"os"
 	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+
+	defaultconfigs "github.com/cmmarslender/edgefig/default-configs"
+	"github.com/cmmarslender/edgefig/internal/backup"
+	"github.com/cmmarslender/edgefig/pkg/config"
+	"github.com/cmmarslender/edgefig/pkg/edgeconfig"
 )
 
 // rootCmd represents the base command when called without any subcommands
@@ -32,8 +38,109 @@ func init() {
 
 	cobra.OnInitialize(initConfig)
 
+	var limit []string
+	var backupDir string
+	var backupStore string
+	var backupRetention int
+	var output string
+	var logLevel string
+
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "config.yml", "config file (default is config.yml)")
+	rootCmd.PersistentFlags().StringSliceVar(&limit, "limit", nil, "limit to routers matching these names, globs, or tag:<tag> (comma separated)")
 	cobra.CheckErr(viper.BindPFlag("config", rootCmd.PersistentFlags().Lookup("config")))
+	rootCmd.PersistentFlags().StringVar(&backupDir, "backup-dir", "backups", "directory to store router config backups in, with a subfolder per router")
+	rootCmd.PersistentFlags().StringVar(&backupStore, "backup-store", "file", "where to store backups: file (one file per backup) or git (commits to a git repository in the backup directory)")
+	rootCmd.PersistentFlags().IntVar(&backupRetention, "backup-retention", 0, "number of backups to keep per router, 0 keeps all backups")
+	rootCmd.PersistentFlags().StringVar(&output, "output", "text", "result format: text (human readable) or json (a structured result document on stdout)")
+	rootCmd.PersistentFlags().StringVar(&logLevel, "log-level", "info", "minimum level of logs written to stderr: debug, info, warn, or error")
+	cobra.CheckErr(viper.BindPFlag("limit", rootCmd.PersistentFlags().Lookup("limit")))
+	cobra.CheckErr(viper.BindPFlag("backup-dir", rootCmd.PersistentFlags().Lookup("backup-dir")))
+	cobra.CheckErr(viper.BindPFlag("backup-store", rootCmd.PersistentFlags().Lookup("backup-store")))
+	cobra.CheckErr(viper.BindPFlag("backup-retention", rootCmd.PersistentFlags().Lookup("backup-retention")))
+	cobra.CheckErr(viper.BindPFlag("output", rootCmd.PersistentFlags().Lookup("output")))
+	cobra.CheckErr(viper.BindPFlag("log-level", rootCmd.PersistentFlags().Lookup("log-level")))
+}
+
+// newBackupStore returns the backup store configured by the global flags
+func newBackupStore() (backup.Store, error) {
+	switch viper.GetString("backup-store") {
+	case "file":
+		return backup.NewFileStore(viper.GetString("backup-dir"), viper.GetInt("backup-retention")), nil
+	case "git":
+		if viper.GetInt("backup-retention") > 0 {
+			return nil, fmt.Errorf("--backup-retention is not supported by the git backup store, which keeps the full history")
+		}
+		return backup.NewGitStore(viper.GetString("backup-dir"))
+	default:
+		return nil, fmt.Errorf("unknown backup store %s", viper.GetString("backup-store"))
+	}
+}
+
+// loadSelectedRouters loads the config and returns it along with the routers selected by --limit
+func loadSelectedRouters() (*config.Config, []config.Router, error) {
+	cfg, err := config.LoadConfig(viper.GetString("config"))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	routers, err := cfg.SelectRouters(viper.GetStringSlice("limit"))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return cfg, routers, nil
+}
+
+// offlineInterfaces returns the ports to generate the router's config with when there is no router to discover them from
+// When the router's model is set, the ports in the model's default config are used, which are the ports apply discovers
+// on that hardware. Otherwise every port the config uses, either configured under interfaces or named by firewall zones,
+// zone-policy or policy routes, is assumed to exist
+func offlineInterfaces(router config.Router) (map[string]struct{}, error) {
+	interfaces := map[string]struct{}{}
+	for name := range router.Interfaces {
+		interfaces[name] = struct{}{}
+	}
+
+	if router.Model != "" {
+		defaultConfig, err := defaultconfigs.Get(router.Model)
+		if err != nil {
+			return nil, err
+		}
+		tree, err := edgeconfig.ParseTree(defaultConfig)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing default config for %s: %w", router.Model, err)
+		}
+		if section := tree.Child("interfaces"); section != nil {
+			for _, iface := range section.Children {
+				kind, name, _ := strings.Cut(iface.Name, " ")
+				if kind == "ethernet" || kind == "switch" {
+					interfaces[name] = struct{}{}
+				}
+			}
+		}
+		return interfaces, nil
+	}
+
+	var referenced []string
+	for _, zone := range router.Firewall.Zones {
+		referenced = append(referenced, zone.In...)
+		referenced = append(referenced, zone.Out...)
+		referenced = append(referenced, zone.Local...)
+	}
+	for _, zone := range router.Firewall.ZonePolicy {
+		referenced = append(referenced, zone.Interfaces...)
+	}
+	for _, route := range router.PolicyRoutes {
+		referenced = append(referenced, route.Interfaces...)
+	}
+	for _, name := range referenced {
+		// Vifs such as eth1.10 come from the VLANs on their parent port, they aren't ports themselves
+		if !strings.Contains(name, ".") {
+			interfaces[name] = struct{}{}
+		}
+	}
+
+	return interfaces, nil
 }
 
 // initConfig reads in config file and ENV variables if set.
@@ -52,7 +159,10 @@ func initConfig() {
 	viper.AutomaticEnv() // read in environment variables that match
 
 	// If a config file is found, read it in.
-	if err := viper.ReadInConfig(); err == nil {
-		fmt.Fprintln(os.Stderr, "Using config file:", viper.ConfigFileUsed())
+	configErr := viper.ReadInConfig()
+
+	cobra.CheckErr(setupLogging())
+	if configErr == nil {
+		slog.Info("using config file", "path", viper.ConfigFileUsed())
 	}
 }
@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/cmmarslender/edgefig/pkg/config"
+	"github.com/cmmarslender/edgefig/pkg/simulate"
+	"github.com/cmmarslender/edgefig/pkg/types"
+)
+
+var (
+	simulateRouter string
+	simulateIn     string
+	simulateOut    string
+	simulateSrc    string
+	simulateDst    string
+	simulateProto  string
+	simulateSPort  uint16
+	simulateDPort  uint16
+	simulateState  string
+)
+
+// simulateCmd represents the simulate command
+var simulateCmd = &cobra.Command{
+	Use:   "simulate",
+	Short: "Shows whether the generated firewall config for a router would allow a packet",
+	Run: func(cmd *cobra.Command, args []string) {
+		result := newResult("simulate")
+		cfg, err := config.LoadConfig(viper.GetString("config"))
+		if err != nil {
+			failResult(result, codeConfig, err)
+		}
+
+		packet := simulate.Packet{
+			InInterface:     simulateIn,
+			OutInterface:    simulateOut,
+			Protocol:        types.Protocol(simulateProto),
+			SourcePort:      simulateSPort,
+			DestinationPort: simulateDPort,
+			State:           simulateState,
+		}
+		packet.Source, err = netip.ParseAddr(simulateSrc)
+		if err != nil {
+			failResult(result, codeSimulate, fmt.Errorf("invalid source address: %w", err))
+		}
+		packet.Destination, err = netip.ParseAddr(simulateDst)
+		if err != nil {
+			failResult(result, codeSimulate, fmt.Errorf("invalid destination address: %w", err))
+		}
+
+		device := result.device(simulateRouter)
+		device.start()
+		edgecfg, err := translateRouter(cfg, simulateRouter)
+		if err == nil {
+			var verdict *simulate.Verdict
+			verdict, err = simulate.Simulate(edgecfg, packet)
+			if err == nil {
+				device.Simulations = append(device.Simulations, &SimulationResult{Verdict: verdict})
+			}
+			err = withCode(codeSimulate, err)
+		}
+		device.finish(statusOK, err)
+
+		finishResult(result, func(result *Result) {
+			for _, device := range result.Devices {
+				if device.Error != nil {
+					fmt.Printf("%s: ERROR %s\n", device.Name, device.Error.Message)
+					continue
+				}
+				for _, simulation := range device.Simulations {
+					printVerdict(simulation.Verdict, "")
+				}
+			}
+		})
+	},
+}
+
+// printVerdict prints each step the packet took and the final verdict
+func printVerdict(verdict *simulate.Verdict, indent string) {
+	for _, step := range verdict.Steps {
+		fmt.Printf("%s%s\n", indent, step)
+	}
+	packet := verdict.Packet
+	destination := packet.Destination.String()
+	if packet.DestinationPort != 0 {
+		destination = fmt.Sprintf("%s port %d", destination, packet.DestinationPort)
+	}
+	fmt.Printf("%sverdict: %s %s %s -> %s\n", indent, strings.ToUpper(verdict.Action), packet.Protocol, packet.Source, destination)
+	for _, warning := range verdict.Warnings {
+		fmt.Printf("%swarning: %s\n", indent, warning)
+	}
+}
+
+func init() {
+	simulateCmd.Flags().StringVar(&simulateRouter, "router", "", "name of the router to simulate")
+	simulateCmd.Flags().StringVar(&simulateIn, "in", "", "interface the packet arrives on, such as eth0 or eth1.10")
+	simulateCmd.Flags().StringVar(&simulateOut, "out", "", "interface the packet leaves on (default is looked up from connected networks and static routes)")
+	simulateCmd.Flags().StringVar(&simulateSrc, "src", "", "source address of the packet")
+	simulateCmd.Flags().StringVar(&simulateDst, "dst", "", "destination address of the packet")
+	simulateCmd.Flags().StringVar(&simulateProto, "proto", "tcp", "protocol of the packet: tcp, udp, icmp, or icmpv6")
+	simulateCmd.Flags().Uint16Var(&simulateSPort, "sport", 0, "source port of the packet")
+	simulateCmd.Flags().Uint16Var(&simulateDPort, "dport", 0, "destination port of the packet")
+	simulateCmd.Flags().StringVar(&simulateState, "state", simulate.StateNew, "connection state of the packet: new, established, related, or invalid")
+	cobra.CheckErr(simulateCmd.MarkFlagRequired("router"))
+	cobra.CheckErr(simulateCmd.MarkFlagRequired("in"))
+	cobra.CheckErr(simulateCmd.MarkFlagRequired("src"))
+	cobra.CheckErr(simulateCmd.MarkFlagRequired("dst"))
+
+	rootCmd.AddCommand(simulateCmd)
+}
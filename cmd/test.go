@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/cmmarslender/edgefig/pkg/config"
+	"github.com/cmmarslender/edgefig/pkg/edgeconfig"
+	"github.com/cmmarslender/edgefig/pkg/simulate"
+	"github.com/cmmarslender/edgefig/pkg/translate"
+)
+
+// testCmd represents the test command
+var testCmd = &cobra.Command{
+	Use:   "test <assertions file>",
+	Short: "Simulates each packet in an assertions file and checks the firewall takes the expected action",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		result := newResult("test")
+		cfg, err := config.LoadConfig(viper.GetString("config"))
+		if err != nil {
+			failResult(result, codeConfig, err)
+		}
+
+		assertions, err := simulate.LoadAssertions(args[0])
+		if err != nil {
+			failResult(result, codeConfig, err)
+		}
+
+		// Assertions are grouped by router, in the order each router first appears
+		devices := map[string]*DeviceResult{}
+		edgecfgs := map[string]*edgeconfig.Router{}
+		failures := map[string]int{}
+		for _, assertion := range assertions {
+			device, ok := devices[assertion.Router]
+			if !ok {
+				device = result.device(assertion.Router)
+				device.start()
+				devices[assertion.Router] = device
+			}
+			if device.Error != nil {
+				continue
+			}
+
+			edgecfg, ok := edgecfgs[assertion.Router]
+			if !ok {
+				edgecfg, err = translateRouter(cfg, assertion.Router)
+				if err != nil {
+					device.finish(statusFailed, err)
+					continue
+				}
+				edgecfgs[assertion.Router] = edgecfg
+			}
+
+			verdict, err := simulate.Simulate(edgecfg, assertion.Packet())
+			if err != nil {
+				device.finish(statusFailed, withCode(codeSimulate, fmt.Errorf("assertion %q: %w", assertion.Name, err)))
+				continue
+			}
+
+			passed := verdict.Action == assertion.Expect
+			if !passed {
+				failures[assertion.Router]++
+			}
+			device.Simulations = append(device.Simulations, &SimulationResult{
+				Name:    assertion.Name,
+				Expect:  assertion.Expect,
+				Passed:  &passed,
+				Verdict: verdict,
+			})
+		}
+
+		for name, device := range devices {
+			if device.Error != nil {
+				continue
+			}
+			if failures[name] > 0 {
+				device.finish(statusFailed, withCode(codeAssertion, fmt.Errorf("%d of %d assertions failed", failures[name], len(device.Simulations))))
+				continue
+			}
+			device.finish(statusOK, nil)
+		}
+
+		finishResult(result, func(result *Result) {
+			for _, device := range result.Devices {
+				for _, simulation := range device.Simulations {
+					if *simulation.Passed {
+						fmt.Printf("PASS %s: %s\n", device.Name, simulation.Name)
+						continue
+					}
+					fmt.Printf("FAIL %s: %s (expected %s, got %s)\n", device.Name, simulation.Name, simulation.Expect, simulation.Verdict.Action)
+					printVerdict(simulation.Verdict, "    ")
+				}
+				if device.Error != nil {
+					fmt.Printf("%s: ERROR %s\n", device.Name, device.Error.Message)
+				}
+			}
+		})
+	},
+}
+
+// translateRouter generates the config for the named router
+func translateRouter(cfg *config.Config, name string) (*edgeconfig.Router, error) {
+	router, err := cfg.GetRouterByName(name)
+	if err != nil {
+		return nil, withCode(codeConfig, err)
+	}
+
+	interfaces, err := offlineInterfaces(router)
+	if err != nil {
+		return nil, withCode(codeConfig, err)
+	}
+
+	edgecfg, err := translate.ConfigToEdgeConfig(cfg, router, interfaces)
+	return edgecfg, withCode(codeGenerate, err)
+}
+
+func init() {
+	rootCmd.AddCommand(testCmd)
+}
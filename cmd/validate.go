@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/cmmarslender/edgefig/pkg/config"
+	"github.com/cmmarslender/edgefig/pkg/translate"
+)
+
+// validateCmd represents the validate command
+var validateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Generates the config for each router without connecting to it, reporting any errors",
+	Run: func(cmd *cobra.Command, args []string) {
+		result := newResult("validate")
+		cfg, routers, err := loadSelectedRouters()
+		if err != nil {
+			failResult(result, codeConfig, err)
+		}
+
+		for _, router := range routers {
+			device := result.device(router.Name)
+			device.start()
+			device.finish(statusOK, validateRouter(cfg, router))
+		}
+
+		finishResult(result, func(result *Result) {
+			for _, device := range result.Devices {
+				if device.Error != nil {
+					fmt.Printf("%s: ERROR %s\n", device.Name, device.Error.Message)
+					continue
+				}
+				fmt.Printf("%s: ok\n", device.Name)
+			}
+		})
+	},
+}
+
+// validateRouter generates the router's config, including raw snippets, returning any error along the way
+func validateRouter(cfg *config.Config, router config.Router) error {
+	interfaces, err := offlineInterfaces(router)
+	if err != nil {
+		return withCode(codeConfig, err)
+	}
+
+	edgecfg, err := translate.ConfigToEdgeConfig(cfg, router, interfaces)
+	if err != nil {
+		return withCode(codeGenerate, err)
+	}
+
+	_, err = generatedTree(router, edgecfg)
+	return withCode(codeGenerate, err)
+}
+
+func init() {
+	rootCmd.AddCommand(validateCmd)
+}
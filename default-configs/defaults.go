@@ -0,0 +1,31 @@
+// Package defaultconfigs embeds the factory default config.boot files for each supported router model
+package defaultconfigs
+
+import (
+	"embed"
+	"fmt"
+	"sort"
+)
+
+//go:embed edgerouter-infinity er-x-sfp
+var files embed.FS
+
+// Get returns the factory default config.boot for the model
+func Get(model string) ([]byte, error) {
+	contents, err := files.ReadFile(model)
+	if err != nil {
+		return nil, fmt.Errorf("no default config for model %s, known models are %v", model, Models())
+	}
+	return contents, nil
+}
+
+// Models returns the names of all models with a default config
+func Models() []string {
+	entries, _ := files.ReadDir(".")
+	var models []string
+	for _, entry := range entries {
+		models = append(models, entry.Name())
+	}
+	sort.Strings(models)
+	return models
+}
@@ -0,0 +1,66 @@
+package backup
+
+import (
+	"time"
+)
+
+// Backup is a single saved config for a router
+type Backup struct {
+	Router    string `json:"router"`
+	Timestamp int64  `json:"timestamp"`
+	Location  string `json:"location"`
+}
+
+// Time returns the time the backup was taken
+func (b Backup) Time() time.Time {
+	return time.Unix(b.Timestamp, 0)
+}
+
+// Source describes which command fetched the config being backed up
+type Source string
+
+const (
+	// SourceApply is a config saved before applying changes
+	SourceApply Source = "apply"
+	// SourceRollback is a config saved before rolling back to an older backup
+	SourceRollback Source = "rollback"
+	// SourceBackup is a config saved by the backup command
+	SourceBackup Source = "backup"
+	// SourceDrift is a config saved while checking for drift
+	SourceDrift Source = "drift"
+)
+
+// Store saves and retrieves backups of router configs
+type Store interface {
+	// Save stores the config for the router, returning the new backup
+	Save(router string, contents []byte, source Source) (Backup, error)
+	// List returns the backups for the router, newest first
+	List(router string) ([]Backup, error)
+	// Load returns the contents of the backup taken at the given timestamp
+	Load(router string, timestamp int64) ([]byte, error)
+}
+
+// Latest returns the newest backup for the router from the store
+func Latest(store Store, router string) (Backup, error) {
+	backups, err := store.List(router)
+	if err != nil {
+		return Backup{}, err
+	}
+	if len(backups) == 0 {
+		return Backup{}, &NotFoundError{Router: router}
+	}
+	return backups[0], nil
+}
+
+// NotFoundError is returned when a requested backup does not exist
+type NotFoundError struct {
+	Router    string
+	Timestamp int64
+}
+
+func (e *NotFoundError) Error() string {
+	if e.Timestamp == 0 {
+		return "no backups found for " + e.Router
+	}
+	return "no backup found for " + e.Router + " at " + time.Unix(e.Timestamp, 0).UTC().Format(time.RFC3339)
+}
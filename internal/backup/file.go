@@ -0,0 +1,159 @@
+package backup
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const filePrefix = "config.boot."
+
+// FileStore keeps backups as config.boot.<unix timestamp> files in a subfolder per router
+// Backups taken in the same second get a counter suffix, config.boot.<unix timestamp>.<n>
+type FileStore struct {
+	dir       string
+	retention int
+	now       func() time.Time
+}
+
+// NewFileStore returns a store that writes to dir, keeping at most retention backups per router (0 keeps all)
+func NewFileStore(dir string, retention int) *FileStore {
+	return &FileStore{dir: dir, retention: retention, now: time.Now}
+}
+
+// Save writes the config to a new backup file, then removes any backups beyond the retention count
+// The source is not recorded for file backups
+func (f *FileStore) Save(router string, contents []byte, source Source) (Backup, error) {
+	routerDir := filepath.Join(f.dir, router)
+	err := os.MkdirAll(routerDir, 0755)
+	if err != nil {
+		return Backup{}, fmt.Errorf("error creating backup directory: %w", err)
+	}
+
+	// Avoid overwriting a backup taken in the same second, without moving the timestamp away from when the backup was taken
+	timestamp := f.now().Unix()
+	seq := 0
+	for {
+		if _, err := os.Stat(f.path(router, timestamp, seq)); os.IsNotExist(err) {
+			break
+		}
+		seq++
+	}
+
+	backup := Backup{Router: router, Timestamp: timestamp, Location: f.path(router, timestamp, seq)}
+	err = os.WriteFile(backup.Location, contents, 0600)
+	if err != nil {
+		return Backup{}, fmt.Errorf("error writing backup: %w", err)
+	}
+
+	return backup, f.prune(router)
+}
+
+// List returns the backups for the router, newest first
+func (f *FileStore) List(router string) ([]Backup, error) {
+	entries, err := os.ReadDir(filepath.Join(f.dir, router))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error listing backups: %w", err)
+	}
+
+	type fileBackup struct {
+		Backup
+		seq int
+	}
+	var files []fileBackup
+	for _, entry := range entries {
+		suffix, ok := strings.CutPrefix(entry.Name(), filePrefix)
+		if !ok || entry.IsDir() {
+			continue
+		}
+		timestampStr, seqStr, hasSeq := strings.Cut(suffix, ".")
+		timestamp, err := strconv.ParseInt(timestampStr, 10, 64)
+		if err != nil {
+			continue
+		}
+		seq := 0
+		if hasSeq {
+			seq, err = strconv.Atoi(seqStr)
+			if err != nil || seq < 1 {
+				continue
+			}
+		}
+		files = append(files, fileBackup{
+			Backup: Backup{Router: router, Timestamp: timestamp, Location: f.path(router, timestamp, seq)},
+			seq:    seq,
+		})
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		if files[i].Timestamp != files[j].Timestamp {
+			return files[i].Timestamp > files[j].Timestamp
+		}
+		return files[i].seq > files[j].seq
+	})
+
+	var backups []Backup
+	for _, file := range files {
+		backups = append(backups, file.Backup)
+	}
+
+	return backups, nil
+}
+
+// Load reads the newest backup taken at the given timestamp
+func (f *FileStore) Load(router string, timestamp int64) ([]byte, error) {
+	backups, err := f.List(router)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, backup := range backups {
+		if backup.Timestamp == timestamp {
+			contents, err := os.ReadFile(backup.Location)
+			if err != nil {
+				return nil, fmt.Errorf("error reading backup: %w", err)
+			}
+			return contents, nil
+		}
+	}
+
+	return nil, &NotFoundError{Router: router, Timestamp: timestamp}
+}
+
+func (f *FileStore) path(router string, timestamp int64, seq int) string {
+	name := fmt.Sprintf("%s%d", filePrefix, timestamp)
+	if seq > 0 {
+		name = fmt.Sprintf("%s.%d", name, seq)
+	}
+	return filepath.Join(f.dir, router, name)
+}
+
+// prune removes the oldest backups beyond the retention count
+func (f *FileStore) prune(router string) error {
+	if f.retention <= 0 {
+		return nil
+	}
+
+	backups, err := f.List(router)
+	if err != nil {
+		return err
+	}
+	if len(backups) <= f.retention {
+		return nil
+	}
+
+	for _, backup := range backups[f.retention:] {
+		err = os.Remove(backup.Location)
+		if err != nil {
+			return fmt.Errorf("error removing old backup: %w", err)
+		}
+	}
+
+	return nil
+}
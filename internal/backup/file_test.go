@@ -0,0 +1,174 @@
+package backup
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+// testStore returns a file store in a temporary directory whose clock reads from now
+func testStore(t *testing.T, retention int, now *time.Time) *FileStore {
+	t.Helper()
+	store := NewFileStore(t.TempDir(), retention)
+	store.now = func() time.Time { return *now }
+	return store
+}
+
+func TestFileStoreCollision(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	store := testStore(t, 0, &now)
+
+	var saved []Backup
+	for _, contents := range []string{"first", "second", "third"} {
+		backup, err := store.Save("router01", []byte(contents), SourceApply)
+		if err != nil {
+			t.Fatal(err)
+		}
+		saved = append(saved, backup)
+	}
+
+	for _, backup := range saved {
+		if backup.Timestamp != now.Unix() {
+			t.Errorf("Timestamp = %d, want %d; backups shouldn't be dated after they were taken", backup.Timestamp, now.Unix())
+		}
+	}
+	wantNames := []string{"config.boot.1700000000", "config.boot.1700000000.1", "config.boot.1700000000.2"}
+	for i, backup := range saved {
+		if name := filepath.Base(backup.Location); name != wantNames[i] {
+			t.Errorf("backup %d saved as %s, want %s", i, name, wantNames[i])
+		}
+	}
+
+	contents, err := store.Load("router01", now.Unix())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(contents) != "third" {
+		t.Errorf("Load() = %q, want the newest backup from that second", contents)
+	}
+}
+
+func TestFileStoreList(t *testing.T) {
+	store := NewFileStore(t.TempDir(), 0)
+	routerDir := filepath.Join(store.dir, "router01")
+	if err := os.MkdirAll(filepath.Join(routerDir, "config.boot.1700000300"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{
+		"config.boot.1700000100",
+		"config.boot.1700000200.1",
+		"config.boot.99",
+		"config.boot.1700000200",
+		"config.boot.1700000200.2",
+		"config.boot.1700000100.x",
+		"config.boot.latest",
+		"notes.txt",
+	} {
+		if err := os.WriteFile(filepath.Join(routerDir, name), nil, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	backups, err := store.List("router01")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, backup := range backups {
+		got = append(got, filepath.Base(backup.Location))
+	}
+	want := []string{
+		"config.boot.1700000200.2",
+		"config.boot.1700000200.1",
+		"config.boot.1700000200",
+		"config.boot.1700000100",
+		"config.boot.99",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("List() = %q, want %q", got, want)
+	}
+
+	backups, err = store.List("router02")
+	if err != nil || backups != nil {
+		t.Errorf("List() for a router without backups = %v, %v, want nil, nil", backups, err)
+	}
+}
+
+func TestFileStorePrune(t *testing.T) {
+	tests := []struct {
+		name      string
+		retention int
+		saves     []int64
+		want      []string
+	}{
+		{
+			name:      "keeps everything without retention",
+			retention: 0,
+			saves:     []int64{100, 200, 300},
+			want:      []string{"config.boot.300", "config.boot.200", "config.boot.100"},
+		},
+		{
+			name:      "removes the oldest",
+			retention: 2,
+			saves:     []int64{100, 200, 300, 400},
+			want:      []string{"config.boot.400", "config.boot.300"},
+		},
+		{
+			name:      "counts backups from the same second",
+			retention: 2,
+			saves:     []int64{100, 200, 200, 200},
+			want:      []string{"config.boot.200.2", "config.boot.200.1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var now time.Time
+			store := testStore(t, tt.retention, &now)
+			for _, timestamp := range tt.saves {
+				now = time.Unix(timestamp, 0)
+				if _, err := store.Save("router01", []byte("config"), SourceBackup); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			entries, err := os.ReadDir(filepath.Join(store.dir, "router01"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			backups, err := store.List("router01")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != len(backups) {
+				t.Errorf("%d files left on disk, but List() returned %d", len(entries), len(backups))
+			}
+
+			var got []string
+			for _, backup := range backups {
+				got = append(got, filepath.Base(backup.Location))
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("backups after pruning = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFileStoreLoadNotFound(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	store := testStore(t, 0, &now)
+	if _, err := store.Save("router01", []byte("config"), SourceApply); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := store.Load("router01", now.Unix()+1)
+	var notFound *NotFoundError
+	if !errors.As(err, &notFound) {
+		t.Errorf("Load() error = %v, want a NotFoundError", err)
+	}
+}
@@ -0,0 +1,167 @@
+package backup
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// gitLock serializes changes to the repository, since routers are backed up concurrently and git fails when another
+// process holds the index lock
+var gitLock sync.Mutex
+
+// GitStore commits each router's config to <router>.boot in a local git repository, using the git binary
+// Every save is a commit, so the full history of changes (including ones made by hand on the router) is kept
+// Saving a config that hasn't changed since the last backup does not create a new commit
+type GitStore struct {
+	dir string
+}
+
+// NewGitStore returns a store that commits to the git working tree in dir, initializing it if needed
+func NewGitStore(dir string) (*GitStore, error) {
+	if _, err := exec.LookPath("git"); err != nil {
+		return nil, fmt.Errorf("git backup store requires git to be installed: %w", err)
+	}
+
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return nil, fmt.Errorf("error creating backup directory: %w", err)
+	}
+
+	gitLock.Lock()
+	defer gitLock.Unlock()
+
+	g := &GitStore{dir: dir}
+	if _, err := os.Stat(filepath.Join(dir, ".git")); os.IsNotExist(err) {
+		_, err = g.git("init", "--quiet")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return g, nil
+}
+
+// Save writes the config to the router's file and commits it, noting the source in the commit message
+func (g *GitStore) Save(router string, contents []byte, source Source) (Backup, error) {
+	gitLock.Lock()
+	defer gitLock.Unlock()
+
+	err := os.WriteFile(filepath.Join(g.dir, g.file(router)), contents, 0600)
+	if err != nil {
+		return Backup{}, fmt.Errorf("error writing backup: %w", err)
+	}
+
+	_, err = g.git("add", "--", g.file(router))
+	if err != nil {
+		return Backup{}, err
+	}
+
+	// Nothing staged means the config is unchanged since the last backup
+	if _, err := g.git("diff", "--cached", "--quiet", "--", g.file(router)); err == nil {
+		return Latest(g, router)
+	}
+
+	args := append(g.identity(), "commit", "--quiet", "-m", fmt.Sprintf("%s: config fetched by %s", router, source), "--", g.file(router))
+	_, err = g.git(args...)
+	if err != nil {
+		return Backup{}, err
+	}
+
+	return Latest(g, router)
+}
+
+// List returns a backup for every commit that touched the router's file, newest first
+// Location is the commit hash
+func (g *GitStore) List(router string) ([]Backup, error) {
+	if _, err := g.git("rev-parse", "--verify", "--quiet", "HEAD"); err != nil {
+		// No commits yet
+		return nil, nil
+	}
+
+	output, err := g.git("log", "--format=%H %ct", "--", g.file(router))
+	if err != nil {
+		return nil, err
+	}
+
+	var backups []Backup
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		timestamp, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		backups = append(backups, Backup{Router: router, Timestamp: timestamp, Location: fields[0]})
+	}
+
+	return backups, nil
+}
+
+// Load returns the router's file from the newest commit made at the given timestamp
+func (g *GitStore) Load(router string, timestamp int64) ([]byte, error) {
+	backups, err := g.List(router)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, backup := range backups {
+		if backup.Timestamp == timestamp {
+			output, err := g.git("show", fmt.Sprintf("%s:%s", backup.Location, g.file(router)))
+			if err != nil {
+				return nil, err
+			}
+			return []byte(output), nil
+		}
+	}
+
+	return nil, &NotFoundError{Router: router, Timestamp: timestamp}
+}
+
+func (g *GitStore) file(router string) string {
+	return router + ".boot"
+}
+
+// identity supplies a committer identity if the repository or user doesn't already have one configured
+func (g *GitStore) identity() []string {
+	if email, err := g.git("config", "user.email"); err == nil && strings.TrimSpace(email) != "" {
+		return nil
+	}
+	return []string{"-c", "user.name=edgefig", "-c", "user.email=edgefig@localhost"}
+}
+
+// git runs a git subcommand in the repository, where args can start with -c options to pass before the subcommand
+func (g *GitStore) git(args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = g.dir
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	if err != nil {
+		return stdout.String(), fmt.Errorf("error running git %s: %w: %s", subcommand(args), err, strings.TrimSpace(stderr.String()))
+	}
+
+	return stdout.String(), nil
+}
+
+// subcommand returns the git subcommand in args, skipping any "-c name=value" options before it
+func subcommand(args []string) string {
+	for idx := 0; idx < len(args); idx++ {
+		if args[idx] == "-c" {
+			idx++
+			continue
+		}
+		return args[idx]
+	}
+	return ""
+}
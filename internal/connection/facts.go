@@ -0,0 +1,91 @@
+package connection
+
+import (
+	"strings"
+
+	"github.com/cmmarslender/edgefig/pkg/types"
+)
+
+// Facts is information about the device gathered from `show version`
+type Facts struct {
+	Model    string                `json:"model"`
+	Firmware types.FirmwareVersion `json:"firmware"`
+	BuildID  string                `json:"build_id"`
+	Serial   string                `json:"serial"`
+	Uptime   string                `json:"uptime"`
+}
+
+// knownModels maps the hardware model from `show version` to the name of its default config
+var knownModels = map[string]string{
+	"EdgeRouter Infinity": "edgerouter-infinity",
+	"EdgeRouter X SFP":    "er-x-sfp",
+}
+
+// DefaultConfigModel returns the name of the default config for this device's model, if there is one
+func (f *Facts) DefaultConfigModel() string {
+	for prefix, model := range knownModels {
+		if strings.HasPrefix(f.Model, prefix) {
+			return model
+		}
+	}
+	return ""
+}
+
+// ParseFacts parses the output of `show version`
+//
+//	Version:      v2.0.9-hotfix.4
+//	Build ID:     5521907
+//	Build on:     06/30/22 06:57
+//	Copyright:    2012-2020 Ubiquiti Networks, Inc.
+//	HW model:     EdgeRouter X SFP 6-port
+//	HW S/N:       XXXXXXXXXXXX
+//	Uptime:       11:12:35 up 1 day,  2:03,  1 user,  load average: 0.13, 0.10, 0.09
+func ParseFacts(output string) (*Facts, error) {
+	facts := &Facts{}
+	for _, line := range strings.Split(output, "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+
+		switch strings.TrimSpace(key) {
+		case "Version":
+			firmware, err := types.ParseFirmwareVersion(value)
+			if err != nil {
+				return nil, err
+			}
+			facts.Firmware = firmware
+		case "Build ID":
+			facts.BuildID = value
+		case "HW model":
+			facts.Model = value
+		case "HW S/N":
+			facts.Serial = value
+		case "Uptime":
+			facts.Uptime = value
+		}
+	}
+
+	return facts, nil
+}
+
+// Facts gathers facts about the device, caching them for the life of the connection
+func (s *SSHConnection) Facts() (*Facts, error) {
+	if s.facts != nil {
+		return s.facts, nil
+	}
+
+	output, err := s.OpCommand("show version")
+	if err != nil {
+		return nil, err
+	}
+
+	facts, err := ParseFacts(output)
+	if err != nil {
+		return nil, err
+	}
+	s.facts = facts
+
+	return facts, nil
+}
@@ -3,6 +3,7 @@ package connection
 import (
 	"bytes"
 	"fmt"
+	"log/slog"
 	"net/netip"
 	"strings"
 
@@ -12,6 +13,7 @@ import (
 // SSHConnection encapsulates the SSH connection to the devices as well as any commands we run on them
 type SSHConnection struct {
 	connection *ssh.Client
+	facts      *Facts
 }
 
 // NewSSHConnection returns a new SSH connection struct
@@ -28,7 +30,7 @@ func NewSSHConnection(ip netip.Addr, port uint16, user, password string) (*SSHCo
 	}
 
 	// Connecting to the SSH server
-	connection, err := ssh.Dial("tcp", fmt.Sprintf("%s:%d", ip.String(), port), config)
+	connection, err := ssh.Dial("tcp", netip.AddrPortFrom(ip, port).String(), config)
 	if err != nil {
 		return nil, err
 	}
@@ -55,9 +57,42 @@ func (s *SSHConnection) remoteCommand(command string) (*bytes.Buffer, error) {
 	return &b, nil
 }
 
+// logOutput logs anything a remote command printed, keeping stdout free for command results
+func (s *SSHConnection) logOutput(buf *bytes.Buffer) {
+	if buf == nil {
+		return
+	}
+	output := strings.TrimSpace(buf.String())
+	if output == "" {
+		return
+	}
+	slog.Info("remote output", "host", s.connection.RemoteAddr().String(), "output", output)
+}
+
+// OpCommand runs an operational mode command (such as "show interfaces") and returns the output
+func (s *SSHConnection) OpCommand(command string) (string, error) {
+	buf, err := s.remoteCommand(fmt.Sprintf("/opt/vyatta/bin/vyatta-op-cmd-wrapper %s", command))
+	if err != nil {
+		return "", fmt.Errorf("error running %s: %w", command, err)
+	}
+
+	return buf.String(), nil
+}
+
+// Close closes the underlying SSH connection
+func (s *SSHConnection) Close() error {
+	return s.connection.Close()
+}
+
+// Alive returns whether the connection is still open, by sending a keepalive request and waiting for the reply
+func (s *SSHConnection) Alive() bool {
+	_, _, err := s.connection.SendRequest("keepalive@openssh.com", true, nil)
+	return err == nil
+}
+
 // GetAvailablePorts lists out the ports supported on the router
 func (s *SSHConnection) GetAvailablePorts() (map[string]struct{}, error) {
-	buf, err := s.remoteCommand("/opt/vyatta/bin/vyatta-op-cmd-wrapper show interfaces")
+	output, err := s.OpCommand("show interfaces")
 	if err != nil {
 		return nil, fmt.Errorf("error reading ethernet interfaces: %w", err)
 	}
@@ -80,7 +115,6 @@ func (s *SSHConnection) GetAvailablePorts() (map[string]struct{}, error) {
 	ports := map[string]struct{}{}
 
 	// Split the output into lines
-	output := buf.String()
 	lines := strings.Split(output, "\n")
 
 	// Iterate over each line to parse the interfaces
@@ -118,15 +152,15 @@ func (s *SSHConnection) FetchLiveConfig() ([]byte, error) {
 
 // WriteFile writes a file to the remote host
 func (s *SSHConnection) WriteFile(remotePath string, contents []byte) error {
-	buf, err := s.remoteCommand(fmt.Sprintf("echo '%s' > %s", string(contents), remotePath))
-	fmt.Print(buf.String())
+	buf, err := s.remoteCommand(fmt.Sprintf("printf '%%s' %s > %s", shellQuote(string(contents)), shellQuote(remotePath)))
+	s.logOutput(buf)
 	return err
 }
 
 // DeleteFile deletes a file on the remote host
 func (s *SSHConnection) DeleteFile(remotePath string) error {
 	buf, err := s.remoteCommand(fmt.Sprintf("rm %s", remotePath))
-	fmt.Print(buf.String())
+	s.logOutput(buf)
 	return err
 }
 
@@ -141,9 +175,7 @@ func (s *SSHConnection) ApplyConfig(configPath string) error {
 
 	for _, cmd := range commands {
 		buf, err := s.remoteCommand(cmd)
-		if buf != nil {
-			fmt.Print(buf.String())
-		}
+		s.logOutput(buf)
 		if err != nil {
 			return fmt.Errorf("error running command %s: %w", cmd, err)
 		}
@@ -151,3 +183,69 @@ func (s *SSHConnection) ApplyConfig(configPath string) error {
 
 	return nil
 }
+
+// shellWords splits a set/delete command into its words, where a double quoted value is a single word without its quotes
+func shellWords(command string) []string {
+	var words []string
+	var current strings.Builder
+	inQuotes := false
+	quoted := false
+
+	for _, r := range command {
+		switch {
+		case r == '"':
+			inQuotes = !inQuotes
+			quoted = true
+		case (r == ' ' || r == '\t') && !inQuotes:
+			if current.Len() > 0 || quoted {
+				words = append(words, current.String())
+				current.Reset()
+				quoted = false
+			}
+		default:
+			current.WriteRune(r)
+		}
+	}
+	if current.Len() > 0 || quoted {
+		words = append(words, current.String())
+	}
+
+	return words
+}
+
+// shellQuote quotes the value so the remote shell passes it through as-is, as a single argument
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+// ApplyCommands runs set/delete commands inside a single configure session, then commits and saves
+// The commands run in one remote shell so the session is shared, and any failure discards uncommitted changes
+// Every word is shell quoted, so values like password hashes ($1$...) and descriptions reach the router unchanged
+func (s *SSHConnection) ApplyCommands(commands []string) error {
+	wrapper := "/opt/vyatta/sbin/vyatta-cfg-cmd-wrapper"
+
+	script := []string{
+		"set -e",
+		fmt.Sprintf("trap '%s end' EXIT", wrapper),
+		fmt.Sprintf("%s begin", wrapper),
+	}
+	for _, cmd := range commands {
+		args := shellWords(cmd)
+		for idx, arg := range args {
+			args[idx] = shellQuote(arg)
+		}
+		script = append(script, fmt.Sprintf("%s %s", wrapper, strings.Join(args, " ")))
+	}
+	script = append(script,
+		fmt.Sprintf("%s commit", wrapper),
+		fmt.Sprintf("%s save", wrapper),
+	)
+
+	buf, err := s.remoteCommand(strings.Join(script, "\n"))
+	s.logOutput(buf)
+	if err != nil {
+		return fmt.Errorf("error applying commands: %w", err)
+	}
+
+	return nil
+}
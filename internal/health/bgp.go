@@ -0,0 +1,81 @@
+package health
+
+import (
+	"net/netip"
+	"strconv"
+	"strings"
+)
+
+// BGPNeighborStatus is the state of a single neighbor from the bgp summary
+type BGPNeighborStatus struct {
+	IP               netip.Addr `json:"ip"`
+	ASN              uint32     `json:"asn"`
+	UpDown           string     `json:"up_down"`
+	State            string     `json:"state"`
+	Established      bool       `json:"established"`
+	PrefixesReceived int        `json:"prefixes_received"`
+}
+
+// ParseBGPSummary parses the neighbor table from `show ip bgp summary` or `show ipv6 bgp summary`
+//
+//	Neighbor        V    AS   MsgRcv    MsgSen TblVer   InQ   OutQ    Up/Down   State/PfxRcd
+//	203.0.113.1     4 65537     1000       900     21     0      0   1d02h03m              3
+//	203.0.113.5     4 65538        0         0      0     0      0      never         Active
+//
+// Long ipv6 neighbor addresses are printed on their own line, with the remaining columns on the next line
+func ParseBGPSummary(output string) map[netip.Addr]BGPNeighborStatus {
+	neighbors := map[netip.Addr]BGPNeighborStatus{}
+	inTable := false
+	var wrapped netip.Addr
+
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if fields[0] == "Neighbor" {
+			inTable = true
+			continue
+		}
+		if !inTable {
+			continue
+		}
+
+		if wrapped.IsValid() {
+			fields = append([]string{wrapped.String()}, fields...)
+			wrapped = netip.Addr{}
+		}
+
+		ip, err := netip.ParseAddr(fields[0])
+		if err != nil {
+			continue
+		}
+		if len(fields) == 1 {
+			wrapped = ip
+			continue
+		}
+		if len(fields) < 10 {
+			continue
+		}
+
+		status := BGPNeighborStatus{
+			IP:     ip,
+			UpDown: fields[len(fields)-2],
+			State:  fields[len(fields)-1],
+		}
+		if asn, err := strconv.ParseUint(fields[2], 10, 32); err == nil {
+			status.ASN = uint32(asn)
+		}
+
+		// The last column is a prefix count once the session is established, or the session state otherwise
+		if received, err := strconv.Atoi(status.State); err == nil {
+			status.Established = true
+			status.State = "Established"
+			status.PrefixesReceived = received
+		}
+
+		neighbors[ip] = status
+	}
+
+	return neighbors
+}
@@ -0,0 +1,52 @@
+package health
+
+import (
+	"maps"
+	"net/netip"
+	"testing"
+)
+
+func TestParseBGPSummary(t *testing.T) {
+	tests := []struct {
+		name    string
+		fixture string
+		want    map[netip.Addr]BGPNeighborStatus
+	}{
+		{
+			name:    "healthy",
+			fixture: "bgp-summary-healthy.txt",
+			want: map[netip.Addr]BGPNeighborStatus{
+				netip.MustParseAddr("203.0.113.1"): {IP: netip.MustParseAddr("203.0.113.1"), ASN: 65537, UpDown: "1d02h03m", State: "Established", Established: true, PrefixesReceived: 3},
+				netip.MustParseAddr("203.0.113.5"): {IP: netip.MustParseAddr("203.0.113.5"), ASN: 65538, UpDown: "05:12:44", State: "Established", Established: true, PrefixesReceived: 12},
+			},
+		},
+		{
+			name:    "degraded",
+			fixture: "bgp-summary-degraded.txt",
+			want: map[netip.Addr]BGPNeighborStatus{
+				netip.MustParseAddr("203.0.113.1"): {IP: netip.MustParseAddr("203.0.113.1"), ASN: 65537, UpDown: "1d02h03m", State: "Established", Established: true, PrefixesReceived: 3},
+				netip.MustParseAddr("203.0.113.5"): {IP: netip.MustParseAddr("203.0.113.5"), ASN: 65538, UpDown: "never", State: "Active"},
+			},
+		},
+		{
+			name:    "wrapped ipv6 neighbor",
+			fixture: "bgpv6-summary-healthy.txt",
+			want: map[netip.Addr]BGPNeighborStatus{
+				netip.MustParseAddr("2001:db8:ffff:ffff::1"): {IP: netip.MustParseAddr("2001:db8:ffff:ffff::1"), ASN: 65537, UpDown: "1d02h03m", State: "Established", Established: true, PrefixesReceived: 5},
+			},
+		},
+		{
+			name:    "unparseable",
+			fixture: "unparseable.txt",
+			want:    map[netip.Addr]BGPNeighborStatus{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseBGPSummary(fixture(t, tt.fixture)); !maps.Equal(got, tt.want) {
+				t.Errorf("ParseBGPSummary() =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package health
+
+import (
+	"net/netip"
+	"strings"
+)
+
+// DHCPLease is a single lease from `show dhcp leases`
+type DHCPLease struct {
+	IP         netip.Addr `json:"ip"`
+	MAC        string     `json:"mac"`
+	Expiration string     `json:"expiration"`
+	Pool       string     `json:"pool"`
+	ClientName string     `json:"client_name"`
+}
+
+// ParseDHCPLeases parses the output of `show dhcp leases`
+// Returns false if the output indicates the DHCP server is not running
+//
+//	IP address      Hardware Address   Lease expiration     Pool       Client Name
+//	----------      ----------------   ----------------     ----       -----------
+//	192.0.2.150     00:00:5e:00:53:01  2024/01/02 10:00:00  LAN        host1
+func ParseDHCPLeases(output string) ([]DHCPLease, bool) {
+	lower := strings.ToLower(output)
+	if strings.Contains(lower, "not running") || strings.Contains(lower, "not configured") {
+		return nil, false
+	}
+
+	var leases []DHCPLease
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 5 {
+			continue
+		}
+		ip, err := netip.ParseAddr(fields[0])
+		if err != nil {
+			continue
+		}
+
+		lease := DHCPLease{
+			IP:         ip,
+			MAC:        fields[1],
+			Expiration: fields[2] + " " + fields[3],
+			Pool:       fields[4],
+		}
+		if len(fields) > 5 {
+			lease.ClientName = strings.Join(fields[5:], " ")
+		}
+		leases = append(leases, lease)
+	}
+
+	return leases, true
+}
@@ -0,0 +1,48 @@
+package health
+
+import (
+	"net/netip"
+	"slices"
+	"testing"
+)
+
+func TestParseDHCPLeases(t *testing.T) {
+	tests := []struct {
+		name        string
+		fixture     string
+		want        []DHCPLease
+		wantRunning bool
+	}{
+		{
+			name:    "healthy",
+			fixture: "dhcp-leases-healthy.txt",
+			want: []DHCPLease{
+				{IP: netip.MustParseAddr("192.0.2.150"), MAC: "00:00:5e:00:53:01", Expiration: "2024/01/02 10:00:00", Pool: "LAN", ClientName: "host1"},
+				{IP: netip.MustParseAddr("192.0.2.151"), MAC: "00:00:5e:00:53:02", Expiration: "2024/01/02 11:30:00", Pool: "LAN"},
+			},
+			wantRunning: true,
+		},
+		{
+			name:        "not running",
+			fixture:     "dhcp-leases-not-running.txt",
+			wantRunning: false,
+		},
+		{
+			name:        "unparseable",
+			fixture:     "unparseable.txt",
+			wantRunning: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, running := ParseDHCPLeases(fixture(t, tt.fixture))
+			if running != tt.wantRunning {
+				t.Errorf("ParseDHCPLeases() running = %t, want %t", running, tt.wantRunning)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("ParseDHCPLeases() =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,206 @@
+package health
+
+import (
+	"fmt"
+	"log/slog"
+	"net/netip"
+	"strings"
+	"time"
+
+	"github.com/cmmarslender/edgefig/internal/connection"
+	"github.com/cmmarslender/edgefig/pkg/config"
+)
+
+// Report is the result of running every health check against a single router
+type Report struct {
+	Router       string                           `json:"router"`
+	Interfaces   map[string]InterfaceStatus       `json:"interfaces"`
+	BGPNeighbors map[netip.Addr]BGPNeighborStatus `json:"bgp_neighbors"`
+	DHCPRunning  bool                             `json:"dhcp_running"`
+	DHCPLeases   []DHCPLease                      `json:"dhcp_leases"`
+	Failures     []string                         `json:"failures"`
+}
+
+// Healthy returns true if none of the checks failed
+func (r *Report) Healthy() bool {
+	return len(r.Failures) == 0
+}
+
+// Error summarizes the failures, or returns nil if the router is healthy
+func (r *Report) Error() error {
+	if r.Healthy() {
+		return nil
+	}
+	return fmt.Errorf("%s is unhealthy: %s", r.Router, strings.Join(r.Failures, "; "))
+}
+
+func (r *Report) fail(format string, args ...interface{}) {
+	r.Failures = append(r.Failures, fmt.Sprintf(format, args...))
+}
+
+// Gate waits for the router to pass its health checks, returning the last report along with an error
+// if it doesn't pass before the timeout
+// Checks run over ssh, the connection the config was applied over, until it drops; after that each attempt opens a new connection
+func Gate(ssh *connection.SSHConnection, router config.Router, timeout, interval time.Duration) (*Report, error) {
+	return gate(router, timeout, interval, func() (*Report, error) {
+		if ssh.Alive() {
+			return Check(ssh, router)
+		}
+		return Connect(router)
+	})
+}
+
+// gate runs attempt every interval until it returns a healthy report or the timeout passes
+func gate(router config.Router, timeout, interval time.Duration, attempt func() (*Report, error)) (*Report, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		report, err := attempt()
+		if err == nil {
+			err = report.Error()
+		}
+		if err == nil {
+			return report, nil
+		}
+		if time.Now().Add(interval).After(deadline) {
+			return report, fmt.Errorf("%s did not become healthy within %s: %w", router.Name, timeout, err)
+		}
+
+		slog.Info("router is not healthy yet", "router", router.Name, "retry_in", interval.String(), "error", err.Error())
+		time.Sleep(interval)
+	}
+}
+
+// Connect opens a new ssh connection to the router and runs every check against it
+// An unreachable router is reported as a failure rather than an error
+func Connect(router config.Router) (*Report, error) {
+	ssh, err := connection.NewSSHConnection(router.IP, router.Port, router.Username, router.Password)
+	if err != nil {
+		report := &Report{Router: router.Name}
+		report.fail("not reachable over ssh: %s", err.Error())
+		return report, nil
+	}
+	defer func(ssh *connection.SSHConnection) {
+		_ = ssh.Close()
+	}(ssh)
+
+	return Check(ssh, router)
+}
+
+// Check runs every health check over an existing connection, asserting the expectations derived from the config
+// Errors are only returned if a command could not be run; failed expectations are recorded on the report
+func Check(ssh *connection.SSHConnection, router config.Router) (*Report, error) {
+	return check(ssh, router)
+}
+
+// opRunner runs operational mode commands on a router
+type opRunner interface {
+	OpCommand(command string) (string, error)
+}
+
+func check(ssh opRunner, router config.Router) (*Report, error) {
+	report := &Report{Router: router.Name}
+
+	checks := []func(opRunner, config.Router, *Report) error{
+		checkInterfaces,
+		checkBGP,
+		checkDHCP,
+	}
+	for _, check := range checks {
+		err := check(ssh, router, report)
+		if err != nil {
+			return report, err
+		}
+	}
+
+	return report, nil
+}
+
+// checkInterfaces ensures every interface in the config is administratively up with link
+func checkInterfaces(ssh opRunner, router config.Router, report *Report) error {
+	output, err := ssh.OpCommand("show interfaces")
+	if err != nil {
+		return err
+	}
+	report.Interfaces = ParseInterfaces(output)
+
+	for name := range router.Interfaces {
+		status, ok := report.Interfaces[name]
+		if !ok {
+			report.fail("interface %s not found", name)
+			continue
+		}
+		if !status.AdminUp || !status.LinkUp {
+			report.fail("interface %s is not up", name)
+		}
+	}
+
+	return nil
+}
+
+// checkBGP ensures every BGP peer in the config has an established session
+func checkBGP(ssh opRunner, router config.Router, report *Report) error {
+	hasV4, hasV6 := false, false
+	for _, bgpCfg := range router.BGP {
+		for _, peer := range bgpCfg.Peers {
+			if peer.IP.Is6() {
+				hasV6 = true
+			} else {
+				hasV4 = true
+			}
+		}
+	}
+
+	report.BGPNeighbors = map[netip.Addr]BGPNeighborStatus{}
+	for _, summary := range []struct {
+		enabled bool
+		command string
+	}{
+		{hasV4, "show ip bgp summary"},
+		{hasV6, "show ipv6 bgp summary"},
+	} {
+		if !summary.enabled {
+			continue
+		}
+		output, err := ssh.OpCommand(summary.command)
+		if err != nil {
+			return err
+		}
+		for ip, status := range ParseBGPSummary(output) {
+			report.BGPNeighbors[ip] = status
+		}
+	}
+
+	for _, bgpCfg := range router.BGP {
+		for _, peer := range bgpCfg.Peers {
+			status, ok := report.BGPNeighbors[peer.IP]
+			if !ok {
+				report.fail("bgp peer %s not found", peer.IP.String())
+				continue
+			}
+			if !status.Established {
+				report.fail("bgp peer %s is %s", peer.IP.String(), status.State)
+			}
+		}
+	}
+
+	return nil
+}
+
+// checkDHCP ensures the DHCP server is running if any DHCP networks are configured
+func checkDHCP(ssh opRunner, router config.Router, report *Report) error {
+	if len(router.DHCP) == 0 {
+		return nil
+	}
+
+	output, err := ssh.OpCommand("show dhcp leases")
+	if err != nil {
+		return err
+	}
+	report.DHCPLeases, report.DHCPRunning = ParseDHCPLeases(output)
+
+	if !report.DHCPRunning {
+		report.fail("dhcp server is not running")
+	}
+
+	return nil
+}
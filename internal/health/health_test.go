@@ -0,0 +1,198 @@
+package health
+
+import (
+	"errors"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/cmmarslender/edgefig/pkg/config"
+)
+
+// fixture returns captured command output from testdata
+func fixture(t *testing.T, name string) string {
+	t.Helper()
+	output, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(output)
+}
+
+// fixtureRunner answers operational commands with the fixture mapped to each command
+type fixtureRunner struct {
+	t        *testing.T
+	fixtures map[string]string
+}
+
+func (r fixtureRunner) OpCommand(command string) (string, error) {
+	name, ok := r.fixtures[command]
+	if !ok {
+		return "", errors.New("unexpected command " + command)
+	}
+	return fixture(r.t, name), nil
+}
+
+var healthyFixtures = map[string]string{
+	"show interfaces":       "interfaces-healthy.txt",
+	"show ip bgp summary":   "bgp-summary-healthy.txt",
+	"show ipv6 bgp summary": "bgpv6-summary-healthy.txt",
+	"show dhcp leases":      "dhcp-leases-healthy.txt",
+}
+
+// withFixture returns the healthy fixtures with a single command's output replaced
+func withFixture(command, name string) map[string]string {
+	fixtures := map[string]string{}
+	for c, n := range healthyFixtures {
+		fixtures[c] = n
+	}
+	fixtures[command] = name
+	return fixtures
+}
+
+func testRouter() config.Router {
+	return config.Router{
+		Name: "router01",
+		Interfaces: map[string]config.RouterInterface{
+			"eth0": {},
+			"eth1": {},
+		},
+		BGP: []config.BGP{{
+			ASN: 65535,
+			Peers: []config.BGPPeer{
+				{IP: netip.MustParseAddr("203.0.113.1"), ASN: 65537},
+				{IP: netip.MustParseAddr("203.0.113.5"), ASN: 65538},
+				{IP: netip.MustParseAddr("2001:db8:ffff:ffff::1"), ASN: 65537},
+			},
+		}},
+		DHCP: []config.DHCP{{}},
+	}
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name     string
+		fixtures map[string]string
+		want     []string
+	}{
+		{
+			name:     "healthy",
+			fixtures: healthyFixtures,
+		},
+		{
+			name:     "interface without link",
+			fixtures: withFixture("show interfaces", "interfaces-degraded.txt"),
+			want:     []string{"interface eth1 is not up"},
+		},
+		{
+			name:     "bgp session not established",
+			fixtures: withFixture("show ip bgp summary", "bgp-summary-degraded.txt"),
+			want:     []string{"bgp peer 203.0.113.5 is Active"},
+		},
+		{
+			name:     "dhcp server not running",
+			fixtures: withFixture("show dhcp leases", "dhcp-leases-not-running.txt"),
+			want:     []string{"dhcp server is not running"},
+		},
+		{
+			name:     "unparseable interfaces",
+			fixtures: withFixture("show interfaces", "unparseable.txt"),
+			want:     []string{"interface eth0 not found", "interface eth1 not found"},
+		},
+		{
+			name:     "unparseable bgp summary",
+			fixtures: withFixture("show ip bgp summary", "unparseable.txt"),
+			want:     []string{"bgp peer 203.0.113.1 not found", "bgp peer 203.0.113.5 not found"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report, err := check(fixtureRunner{t: t, fixtures: tt.fixtures}, testRouter())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// Interfaces are checked in map order
+			slices.Sort(report.Failures)
+			if !slices.Equal(report.Failures, tt.want) {
+				t.Errorf("Failures = %q, want %q", report.Failures, tt.want)
+			}
+			if report.Healthy() != (len(tt.want) == 0) {
+				t.Errorf("Healthy() = %t, want %t", report.Healthy(), len(tt.want) == 0)
+			}
+		})
+	}
+}
+
+func TestCheckCommandError(t *testing.T) {
+	fixtures := map[string]string{"show ip bgp summary": "bgp-summary-healthy.txt"}
+
+	_, err := check(fixtureRunner{t: t, fixtures: fixtures}, testRouter())
+	if err == nil {
+		t.Fatal("check() expected an error when a command can't be run")
+	}
+}
+
+func TestGate(t *testing.T) {
+	degraded := withFixture("show ip bgp summary", "bgp-summary-degraded.txt")
+
+	tests := []struct {
+		name        string
+		attempts    []map[string]string
+		wantErr     bool
+		wantHealthy bool
+		wantCalls   int // an exact count once healthy, otherwise the least number of attempts
+	}{
+		{
+			name:        "healthy on the first attempt",
+			attempts:    []map[string]string{healthyFixtures},
+			wantHealthy: true,
+			wantCalls:   1,
+		},
+		{
+			name:        "healthy after retrying",
+			attempts:    []map[string]string{degraded, degraded, healthyFixtures},
+			wantHealthy: true,
+			wantCalls:   3,
+		},
+		{
+			name:      "never healthy",
+			attempts:  []map[string]string{degraded},
+			wantErr:   true,
+			wantCalls: 2,
+		},
+		{
+			name:      "unparseable output",
+			attempts:  []map[string]string{withFixture("show interfaces", "unparseable.txt")},
+			wantErr:   true,
+			wantCalls: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			report, err := gate(testRouter(), 25*time.Millisecond, 10*time.Millisecond, func() (*Report, error) {
+				fixtures := tt.attempts[min(calls, len(tt.attempts)-1)]
+				calls++
+				return check(fixtureRunner{t: t, fixtures: fixtures}, testRouter())
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("gate() error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if report == nil {
+				t.Fatal("gate() should return the last report")
+			}
+			if report.Healthy() != tt.wantHealthy {
+				t.Errorf("Healthy() = %t, want %t", report.Healthy(), tt.wantHealthy)
+			}
+			if calls != tt.wantCalls && (!tt.wantErr || calls < tt.wantCalls) {
+				t.Errorf("gate() made %d attempts, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
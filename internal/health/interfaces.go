@@ -0,0 +1,83 @@
+package health
+
+import (
+	"strings"
+)
+
+// InterfaceStatus is the state of a single interface from `show interfaces`
+type InterfaceStatus struct {
+	Name        string   `json:"name"`
+	Addresses   []string `json:"addresses"`
+	AdminUp     bool     `json:"admin_up"`
+	LinkUp      bool     `json:"link_up"`
+	Description string   `json:"description"`
+}
+
+// ParseInterfaces parses the output of `show interfaces`
+//
+//	Codes: S - State, L - Link, u - Up, D - Down, A - Admin Down
+//	Interface    IP Address                        S/L  Description
+//	---------    ----------                        ---  -----------
+//	eth0         192.168.1.1/24                    u/u  Static Config
+//	eth1         10.0.0.3/22                       u/D  WAN
+//	lo           127.0.0.1/8                       u/u
+//	             ::1/128
+func ParseInterfaces(output string) map[string]InterfaceStatus {
+	interfaces := map[string]InterfaceStatus{}
+	var current *InterfaceStatus
+
+	for _, line := range strings.Split(output, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		fields := strings.Fields(line)
+
+		// Continuation lines (additional addresses) start with whitespace
+		if line[0] == ' ' || line[0] == '\t' {
+			if current != nil && len(fields) > 0 {
+				current.Addresses = append(current.Addresses, fields[0])
+				interfaces[current.Name] = *current
+			}
+			continue
+		}
+
+		stateIdx := -1
+		for idx, field := range fields {
+			if isStateField(field) {
+				stateIdx = idx
+				break
+			}
+		}
+		if stateIdx < 1 {
+			// Header or legend lines
+			current = nil
+			continue
+		}
+
+		status := InterfaceStatus{
+			Name:        fields[0],
+			AdminUp:     fields[stateIdx][0] == 'u',
+			LinkUp:      fields[stateIdx][2] == 'u',
+			Description: strings.Join(fields[stateIdx+1:], " "),
+		}
+		for _, address := range fields[1:stateIdx] {
+			if address != "-" {
+				status.Addresses = append(status.Addresses, address)
+			}
+		}
+
+		interfaces[status.Name] = status
+		current = &status
+	}
+
+	return interfaces
+}
+
+// isStateField checks for the S/L column, such as u/u, u/D or A/D
+func isStateField(field string) bool {
+	if len(field) != 3 || field[1] != '/' {
+		return false
+	}
+	return strings.ContainsRune("uDA", rune(field[0])) && strings.ContainsRune("uD", rune(field[2]))
+}
@@ -0,0 +1,58 @@
+package health
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseInterfaces(t *testing.T) {
+	tests := []struct {
+		name    string
+		fixture string
+		want    map[string]InterfaceStatus
+	}{
+		{
+			name:    "healthy",
+			fixture: "interfaces-healthy.txt",
+			want: map[string]InterfaceStatus{
+				"eth0": {Name: "eth0", Addresses: []string{"203.0.113.2/30", "2001:db8:ffff::2/64"}, AdminUp: true, LinkUp: true, Description: "WAN"},
+				"eth1": {Name: "eth1", Addresses: []string{"192.0.2.1/24"}, AdminUp: true, LinkUp: true, Description: "LAN Clients"},
+				"eth2": {Name: "eth2", AdminUp: true},
+				"eth3": {Name: "eth3"},
+				"lo":   {Name: "lo", Addresses: []string{"127.0.0.1/8", "::1/128"}, AdminUp: true, LinkUp: true},
+			},
+		},
+		{
+			name:    "degraded",
+			fixture: "interfaces-degraded.txt",
+			want: map[string]InterfaceStatus{
+				"eth0": {Name: "eth0", Addresses: []string{"203.0.113.2/30", "2001:db8:ffff::2/64"}, AdminUp: true, LinkUp: true, Description: "WAN"},
+				"eth1": {Name: "eth1", Addresses: []string{"192.0.2.1/24"}, AdminUp: true, Description: "LAN Clients"},
+				"eth2": {Name: "eth2", AdminUp: true},
+				"eth3": {Name: "eth3"},
+				"lo":   {Name: "lo", Addresses: []string{"127.0.0.1/8", "::1/128"}, AdminUp: true, LinkUp: true},
+			},
+		},
+		{
+			name:    "unparseable",
+			fixture: "unparseable.txt",
+			want:    map[string]InterfaceStatus{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ParseInterfaces(fixture(t, tt.fixture))
+			if len(got) != len(tt.want) {
+				t.Fatalf("ParseInterfaces() = %+v, want %+v", got, tt.want)
+			}
+			for name, want := range tt.want {
+				status := got[name]
+				if status.Name != want.Name || status.AdminUp != want.AdminUp || status.LinkUp != want.LinkUp ||
+					status.Description != want.Description || !slices.Equal(status.Addresses, want.Addresses) {
+					t.Errorf("ParseInterfaces()[%s] = %+v, want %+v", name, status, want)
+				}
+			}
+		})
+	}
+}
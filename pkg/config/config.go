@@ -4,13 +4,18 @@ import (
 	"fmt"
 	"net/netip"
 
+	"gopkg.in/yaml.v3"
+
 	"github.com/cmmarslender/edgefig/pkg/types"
 )
 
 // Config is the top level config container
+// The top level `profiles` map is resolved into each router while loading, see resolveProfiles
 type Config struct {
-	Routers []Router `yaml:"routers"`
-	VLANs   []VLAN   `yaml:"vlans"`
+	Vars             map[string]interface{} `yaml:"vars"`
+	Routers          []Router               `yaml:"routers"`
+	VLANs            []VLAN                 `yaml:"vlans"`
+	FirewallRulesets []FirewallRuleset      `yaml:"firewall-rulesets"`
 }
 
 // Connection common details for connecting to devices
@@ -30,16 +35,29 @@ type User struct {
 
 // Router is the top level config for a single router
 type Router struct {
-	Name string `yaml:"name"`
+	Name     string                 `yaml:"name"`
+	Tags     []string               `yaml:"tags"`
+	Profiles []string               `yaml:"profiles"`
+	Vars     map[string]interface{} `yaml:"vars"`
+	// Model is the name of the router's default config (such as er-x-sfp), used when the live config can't be relied on
+	Model string `yaml:"model"`
 	Connection
 	Interfaces map[string]RouterInterface `yaml:"interfaces"`
 	Firewall   Firewall                   `yaml:"firewall"`
 	BGP        []BGP                      `yaml:"bgp"`
 	Routes     []StaticRoute              `yaml:"routes"`
-	DHCP       []DHCP                     `yaml:"dhcp"`
-	DNS        DNS                        `yaml:"dns"`
-	NAT        []NAT                      `yaml:"nat"`
-	Users      []User                     `yaml:"users"`
+	// PolicyRoutes send matching traffic to another routing table, instead of routing it by destination alone
+	PolicyRoutes []PolicyRoute `yaml:"policy-routes"`
+	DHCP         []DHCP        `yaml:"dhcp"`
+	DNS          DNS           `yaml:"dns"`
+	NAT          []NAT         `yaml:"nat"`
+	Users        []User        `yaml:"users"`
+	Conntrack    Conntrack     `yaml:"conntrack"`
+	Offload      Offload       `yaml:"offload"`
+	// Unmanaged config paths (such as "service unms") are copied from the live config as-is instead of being generated
+	Unmanaged []string `yaml:"unmanaged"`
+	// Raw config snippets, in config.boot syntax or as `set` commands, merged into the generated config
+	Raw []string `yaml:"raw"`
 }
 
 // RouterInterface is a single physical interface on a router
@@ -67,22 +85,102 @@ type IPv6Prefix struct {
 
 // Firewall config for the router firewall
 type Firewall struct {
-	Groups FirewallGroups `yaml:"groups"`
-	Zones  []FirewallZone `yaml:"zones"`
+	Options    FirewallOptions `yaml:"options"`
+	Groups     FirewallGroups  `yaml:"groups"`
+	Zones      []FirewallZone  `yaml:"zones"`
+	ZonePolicy []PolicyZone    `yaml:"zone-policy"`
+}
+
+// FirewallOptions are the router wide firewall settings
+// Options that aren't set keep their defaults: all-ping, send-redirects and syn-cookies are enabled, everything else
+// is disabled
+type FirewallOptions struct {
+	AllPing          *bool `yaml:"all-ping"`
+	BroadcastPing    *bool `yaml:"broadcast-ping"`
+	LogMartians      *bool `yaml:"log-martians"`
+	ReceiveRedirects *bool `yaml:"receive-redirects"`
+	SendRedirects    *bool `yaml:"send-redirects"`
+	// SourceValidation is strict, loose, or disable (the default)
+	SourceValidation     string `yaml:"source-validation"`
+	IPSrcRoute           *bool  `yaml:"ip-src-route"`
+	IPv6ReceiveRedirects *bool  `yaml:"ipv6-receive-redirects"`
+	IPv6SrcRoute         *bool  `yaml:"ipv6-src-route"`
+	SynCookies           *bool  `yaml:"syn-cookies"`
+}
+
+// PolicyZone is a zone in the zone based firewall, made up of interfaces (or the router itself, for the local zone)
+// Traffic into the zone is filtered by the rulesets listed for the zone it comes from
+type PolicyZone struct {
+	Name          string           `yaml:"name"`
+	Description   string           `yaml:"description"`
+	DefaultAction string           `yaml:"default-action"`
+	Local         bool             `yaml:"local"`
+	Interfaces    []string         `yaml:"interfaces"`
+	From          []PolicyZoneFrom `yaml:"from"`
+}
+
+// PolicyZoneFrom sets the rulesets (firewall zones, by name) applied to traffic coming from another zone
+type PolicyZoneFrom struct {
+	Zone         string `yaml:"zone"`
+	Firewall     string `yaml:"firewall"`
+	IPv6Firewall string `yaml:"ipv6-firewall"`
 }
 
-// FirewallGroups groups of hosts for use in firewall rules
+// FirewallGroups groups of hosts, networks, and ports for use in firewall and NAT rules
 type FirewallGroups struct {
-	AddressGroups []AddressGroup `yaml:"address-groups"`
+	AddressGroups     []AddressGroup `yaml:"address-groups"`
+	NetworkGroups     []NetworkGroup `yaml:"network-groups"`
+	IPv6AddressGroups []AddressGroup `yaml:"ipv6-address-groups"`
+	IPv6NetworkGroups []NetworkGroup `yaml:"ipv6-network-groups"`
+	PortGroups        []PortGroup    `yaml:"port-groups"`
 }
 
-// AddressGroup is an address group for the firewall
+// AddressGroup is a named list of addresses and address ranges for the firewall
 type AddressGroup struct {
-	// @TODO check if this can accept port and all the types of "address"
-	// @TODO Probably should accept mutiple addresses/prefixes/etc? (point of a group right?)
-	Name              string `yaml:"name"`
-	types.AddressPort `yaml:",inline"`
-	Description       string `yaml:"description"`
+	Name        string               `yaml:"name"`
+	Description string               `yaml:"description"`
+	Addresses   []types.GroupAddress `yaml:"addresses"`
+}
+
+// UnmarshalYAML also accepts the older single member shape, where the group set one `address` or `range`
+// instead of a list of `addresses`
+func (g *AddressGroup) UnmarshalYAML(value *yaml.Node) error {
+	type plain AddressGroup
+	var group struct {
+		plain   `yaml:",inline"`
+		Address netip.Addr         `yaml:"address"`
+		Prefix  netip.Prefix       `yaml:"prefix"`
+		Range   types.AddressRange `yaml:"range"`
+	}
+	if err := value.Decode(&group); err != nil {
+		return err
+	}
+
+	*g = AddressGroup(group.plain)
+	if group.Prefix.IsValid() {
+		return fmt.Errorf("address group %s: address groups can't contain prefixes, move %s to a network group", g.Name, group.Prefix)
+	}
+	if group.Address.IsValid() {
+		g.Addresses = append(g.Addresses, types.GroupAddress{Start: group.Address})
+	}
+	if group.Range.Start.IsValid() {
+		g.Addresses = append(g.Addresses, types.GroupAddress{Start: group.Range.Start, End: group.Range.End})
+	}
+	return nil
+}
+
+// NetworkGroup is a named list of prefixes for the firewall
+type NetworkGroup struct {
+	Name        string         `yaml:"name"`
+	Description string         `yaml:"description"`
+	Networks    []netip.Prefix `yaml:"networks"`
+}
+
+// PortGroup is a named list of ports, port ranges, and service names for the firewall
+type PortGroup struct {
+	Name        string       `yaml:"name"`
+	Description string       `yaml:"description"`
+	Ports       []types.Port `yaml:"ports"`
 }
 
 // FirewallZone a single firewall zone
@@ -94,13 +192,25 @@ type FirewallZone struct {
 	In            []string            `yaml:"in"`
 	Out           []string            `yaml:"out"`
 	Local         []string            `yaml:"local"`
+	IncludeBefore []string            `yaml:"include-before"`
 	Rules         []FirewallRule      `yaml:"rules"`
+	IncludeAfter  []string            `yaml:"include-after"`
+}
+
+// FirewallRuleset is a named, reusable list of rules that can be included in any firewall zone
+type FirewallRuleset struct {
+	Name        string         `yaml:"name"`
+	Description string         `yaml:"description"`
+	Rules       []FirewallRule `yaml:"rules"`
 }
 
 // FirewallRule is a single rule within a firewall zone
 type FirewallRule struct {
+	// Number is optional, rules without one are numbered automatically around the rules that have one
+	Number      uint32              `yaml:"number"`
 	Action      string              `yaml:"action"`
 	Description string              `yaml:"description"`
+	Disable     bool                `yaml:"disable"`
 	Destination types.AddressPort   `yaml:"destination"`
 	Source      types.AddressPort   `yaml:"source"`
 	Log         types.EnableDisable `yaml:"log"`
@@ -109,6 +219,42 @@ type FirewallRule struct {
 	Invalid     types.EnableDisable `yaml:"invalid"`
 	New         types.EnableDisable `yaml:"new"`
 	Related     types.EnableDisable `yaml:"related"`
+	ICMP        *FirewallICMP       `yaml:"icmp"`
+	TCPFlags    string              `yaml:"tcp-flags"`
+	Limit       *FirewallLimit      `yaml:"limit"`
+	Recent      *FirewallRecent     `yaml:"recent"`
+	Time        *FirewallTime       `yaml:"time"`
+	// Fragment matches only fragmented packets when true, and only unfragmented packets when false
+	Fragment *bool `yaml:"fragment"`
+	// IPsec matches only packets from ipsec tunnels when true, and only packets not from ipsec when false
+	IPsec *bool `yaml:"ipsec"`
+}
+
+// FirewallICMP matches icmp (or icmpv6) messages by name, or by type and optional code
+type FirewallICMP struct {
+	TypeName string `yaml:"type-name"`
+	Type     *uint8 `yaml:"type"`
+	Code     *uint8 `yaml:"code"`
+}
+
+// FirewallLimit matches packets up to a rate, such as 10/minute, with an optional burst
+type FirewallLimit struct {
+	Rate  string `yaml:"rate"`
+	Burst uint32 `yaml:"burst"`
+}
+
+// FirewallRecent matches sources that have sent at least Count packets in the last Time seconds
+type FirewallRecent struct {
+	Count uint32 `yaml:"count"`
+	Time  uint32 `yaml:"time"`
+}
+
+// FirewallTime matches packets on certain days and times, in router local time unless UTC is set
+type FirewallTime struct {
+	Weekdays  []string `yaml:"weekdays"`
+	StartTime string   `yaml:"start"`
+	StopTime  string   `yaml:"stop"`
+	UTC       bool     `yaml:"utc"`
 }
 
 // BGP Defines a single BGP configuration for an AS
@@ -143,6 +289,85 @@ type StaticRoute struct {
 	NextHop     netip.Addr   `yaml:"next-hop"`
 	Distance    uint8        `yaml:"distance"`
 	Interface   string       `yaml:"interface"`
+	// Table adds the route to a routing table used by policy routes, instead of the main routing table
+	Table uint8 `yaml:"table"`
+}
+
+// PolicyRoute is a modify ruleset that routes matching traffic arriving on its interfaces with another routing table
+type PolicyRoute struct {
+	Name        string            `yaml:"name"`
+	Description string            `yaml:"description"`
+	Interfaces  []string          `yaml:"interfaces"`
+	Rules       []PolicyRouteRule `yaml:"rules"`
+}
+
+// PolicyRouteRule matches traffic to route with a table, or through a next hop
+type PolicyRouteRule struct {
+	// Number is optional, rules without one are numbered automatically around the rules that have one
+	Number      uint32            `yaml:"number"`
+	Description string            `yaml:"description"`
+	Protocol    types.Protocol    `yaml:"protocol"`
+	Source      types.AddressPort `yaml:"source"`
+	Destination types.AddressPort `yaml:"destination"`
+	// Table is the routing table for matching traffic, filled with the router's routes for that table
+	Table uint8 `yaml:"table"`
+	// NextHop routes matching traffic through the next hop, with a default route in Table, or in a table picked
+	// automatically when Table isn't set
+	NextHop netip.Addr `yaml:"next-hop"`
+}
+
+// Conntrack tunes connection tracking, leaving the router's defaults for anything that isn't set
+type Conntrack struct {
+	TableSize       uint32            `yaml:"table-size"`
+	HashSize        uint32            `yaml:"hash-size"`
+	ExpectTableSize uint32            `yaml:"expect-table-size"`
+	Timeouts        ConntrackTimeouts `yaml:"timeouts"`
+	// Modules enables or disables the connection tracking helpers: ftp, gre, h323, pptp, sip, and tftp
+	Modules map[string]bool `yaml:"modules"`
+}
+
+// ConntrackTimeouts are how long connections are tracked for, in seconds
+type ConntrackTimeouts struct {
+	ICMP  uint32               `yaml:"icmp"`
+	Other uint32               `yaml:"other"`
+	TCP   ConntrackTCPTimeouts `yaml:"tcp"`
+	UDP   ConntrackUDPTimeouts `yaml:"udp"`
+}
+
+// ConntrackTCPTimeouts are the timeouts for each state of a tcp connection
+type ConntrackTCPTimeouts struct {
+	Close       uint32 `yaml:"close"`
+	CloseWait   uint32 `yaml:"close-wait"`
+	Established uint32 `yaml:"established"`
+	FinWait     uint32 `yaml:"fin-wait"`
+	LastAck     uint32 `yaml:"last-ack"`
+	SynRecv     uint32 `yaml:"syn-recv"`
+	SynSent     uint32 `yaml:"syn-sent"`
+	TimeWait    uint32 `yaml:"time-wait"`
+}
+
+// ConntrackUDPTimeouts are the timeouts for udp, where stream is used once traffic has been seen in both directions
+type ConntrackUDPTimeouts struct {
+	Other  uint32 `yaml:"other"`
+	Stream uint32 `yaml:"stream"`
+}
+
+// Offload enables hardware offloading, leaving the router's defaults for anything that isn't set
+// Which options work depends on the router's hardware, hwnat is for MediaTek based routers and ipv4/ipv6 for Cavium based routers
+type Offload struct {
+	HWNAT *bool         `yaml:"hwnat"`
+	IPSec *bool         `yaml:"ipsec"`
+	IPv4  OffloadFamily `yaml:"ipv4"`
+	IPv6  OffloadFamily `yaml:"ipv6"`
+}
+
+// OffloadFamily is the forwarding offload for ipv4 or ipv6, where gre and bonding are only supported for ipv4
+type OffloadFamily struct {
+	Forwarding *bool `yaml:"forwarding"`
+	VLAN       *bool `yaml:"vlan"`
+	PPPoE      *bool `yaml:"pppoe"`
+	GRE        *bool `yaml:"gre"`
+	Bonding    *bool `yaml:"bonding"`
 }
 
 // DHCP is a single DHCP config for a single subnet
@@ -181,6 +406,8 @@ type DNSForwarding struct {
 
 // NAT configures NAT rules in a router
 type NAT struct {
+	// Number is optional. Destination rules must be numbered below 5000, and source/masquerade rules from 5000 up
+	Number            uint32            `yaml:"number"`
 	Name              string            `yaml:"name"`
 	Type              types.NATType     `yaml:"type"`
 	InboundInterface  string            `yaml:"inbound_interface"`
@@ -212,3 +439,25 @@ func (c *Config) GetVLANByName(name string) (VLAN, error) {
 
 	return VLAN{}, fmt.Errorf("could not find requested VLAN %s in config", name)
 }
+
+// GetFirewallRulesetByName returns a shared firewall ruleset by its name attribute
+func (c *Config) GetFirewallRulesetByName(name string) (FirewallRuleset, error) {
+	for _, ruleset := range c.FirewallRulesets {
+		if ruleset.Name == name {
+			return ruleset, nil
+		}
+	}
+
+	return FirewallRuleset{}, fmt.Errorf("could not find requested firewall ruleset %s in config", name)
+}
+
+// GetRouterByName returns a router by its name attribute
+func (c *Config) GetRouterByName(name string) (Router, error) {
+	for _, router := range c.Routers {
+		if router.Name == name {
+			return router, nil
+		}
+	}
+
+	return Router{}, fmt.Errorf("could not find requested router %s in config", name)
+}
@@ -0,0 +1,66 @@
+package config
+
+import (
+	"net/netip"
+	"slices"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/cmmarslender/edgefig/pkg/types"
+)
+
+func TestAddressGroupUnmarshal(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		want    []types.GroupAddress
+		wantErr bool
+	}{
+		{
+			name: "addresses list",
+			yaml: "name: servers\naddresses: [192.0.2.10, 192.0.2.20-192.0.2.29]",
+			want: []types.GroupAddress{
+				{Start: netip.MustParseAddr("192.0.2.10")},
+				{Start: netip.MustParseAddr("192.0.2.20"), End: netip.MustParseAddr("192.0.2.29")},
+			},
+		},
+		{
+			name: "single address",
+			yaml: "name: servers\naddress: 192.0.2.10",
+			want: []types.GroupAddress{{Start: netip.MustParseAddr("192.0.2.10")}},
+		},
+		{
+			name: "single range",
+			yaml: "name: servers\nrange: 192.0.2.20-192.0.2.29",
+			want: []types.GroupAddress{{Start: netip.MustParseAddr("192.0.2.20"), End: netip.MustParseAddr("192.0.2.29")}},
+		},
+		{
+			name:    "prefix",
+			yaml:    "name: servers\nprefix: 192.0.2.0/24",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var group AddressGroup
+			err := yaml.Unmarshal([]byte(tt.yaml), &group)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Unmarshal(%q) expected an error", tt.yaml)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unmarshal(%q) returned error: %v", tt.yaml, err)
+			}
+			if group.Name != "servers" {
+				t.Errorf("Name = %q, want servers", group.Name)
+			}
+			if !slices.Equal(group.Addresses, tt.want) {
+				t.Errorf("Addresses = %v, want %v", group.Addresses, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package config
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// tagPrefix marks a --limit pattern as matching routers by tag rather than by name
+const tagPrefix = "tag:"
+
+// SelectRouters returns the routers matching any of the limit patterns, in config order
+// Patterns can be an exact router name, a glob (router-*), or tag:<tag> to match every router with that tag
+// An empty limit selects every router
+func (c *Config) SelectRouters(limit []string) ([]Router, error) {
+	if len(c.Routers) == 0 {
+		return nil, fmt.Errorf("no routers configured")
+	}
+	if len(limit) == 0 {
+		return c.Routers, nil
+	}
+
+	var selected []Router
+	for _, router := range c.Routers {
+		for _, pattern := range limit {
+			matched, err := router.Matches(pattern)
+			if err != nil {
+				return nil, err
+			}
+			if matched {
+				selected = append(selected, router)
+				break
+			}
+		}
+	}
+
+	if len(selected) == 0 {
+		return nil, fmt.Errorf("no routers matched limit %s", strings.Join(limit, ","))
+	}
+
+	return selected, nil
+}
+
+// Matches checks if the router matches a single limit pattern
+func (r *Router) Matches(pattern string) (bool, error) {
+	if tag, ok := strings.CutPrefix(pattern, tagPrefix); ok {
+		return r.HasTag(tag), nil
+	}
+
+	matched, err := path.Match(pattern, r.Name)
+	if err != nil {
+		return false, fmt.Errorf("invalid limit pattern %s: %w", pattern, err)
+	}
+	return matched, nil
+}
+
+// HasTag returns true if the router has the given tag
+func (r *Router) HasTag(tag string) bool {
+	for _, routerTag := range r.Tags {
+		if routerTag == tag {
+			return true
+		}
+	}
+	return false
+}
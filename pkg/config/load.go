@@ -18,9 +18,25 @@ func LoadConfig(configPath string) (*Config, error) {
 		return nil, fmt.Errorf("error reading config file: %w", err)
 	}
 
+	var doc yaml.Node
+	err = yaml.Unmarshal(configBytes, &doc)
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshalling config yaml: %w", err)
+	}
+
+	err = resolveProfiles(&doc)
+	if err != nil {
+		return nil, fmt.Errorf("error resolving profiles: %w", err)
+	}
+
+	err = renderTemplates(&doc)
+	if err != nil {
+		return nil, fmt.Errorf("error rendering config templates: %w", err)
+	}
+
 	config := &Config{}
 
-	err = yaml.Unmarshal(configBytes, config)
+	err = doc.Decode(config)
 	if err != nil {
 		return nil, fmt.Errorf("error unmarshalling config yaml: %w", err)
 	}
@@ -0,0 +1,164 @@
+package config
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// replaceTag can be set on a list in a router or profile to replace inherited values instead of appending to them
+const replaceTag = "!replace"
+
+// resolveProfiles merges the profiles referenced by each router into that router's yaml definition
+//
+// Profiles are applied in the order they are listed on the router, followed by the router itself, using these rules:
+//   - Maps are merged key by key, recursively
+//   - Lists are appended, earlier profiles first and the router's own entries last.
+//     Tag a list with !replace to discard everything inherited before it instead
+//   - Scalar values set later replace values set earlier, so the router always wins
+func resolveProfiles(doc *yaml.Node) error {
+	root := documentRoot(doc)
+	if root == nil {
+		return nil
+	}
+
+	profiles := map[string]*yaml.Node{}
+	if profilesNode := mappingValue(root, "profiles"); profilesNode != nil {
+		if profilesNode.Kind != yaml.MappingNode {
+			return fmt.Errorf("profiles must be a map of profile name to router config")
+		}
+		for i := 0; i+1 < len(profilesNode.Content); i += 2 {
+			profiles[profilesNode.Content[i].Value] = profilesNode.Content[i+1]
+		}
+	}
+
+	routersNode := mappingValue(root, "routers")
+	if routersNode != nil && routersNode.Kind == yaml.SequenceNode {
+		for idx, routerNode := range routersNode.Content {
+			resolved, err := resolveRouterProfiles(routerNode, profiles)
+			if err != nil {
+				return err
+			}
+			routersNode.Content[idx] = resolved
+		}
+	}
+
+	clearReplaceTags(root)
+
+	return nil
+}
+
+func resolveRouterProfiles(routerNode *yaml.Node, profiles map[string]*yaml.Node) (*yaml.Node, error) {
+	profileNames := mappingValue(routerNode, "profiles")
+	if profileNames == nil {
+		return routerNode, nil
+	}
+	if profileNames.Kind != yaml.SequenceNode {
+		return nil, fmt.Errorf("router profiles must be a list of profile names (line %d)", profileNames.Line)
+	}
+
+	resolved := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	for _, nameNode := range profileNames.Content {
+		profile, ok := profiles[nameNode.Value]
+		if !ok {
+			return nil, fmt.Errorf("could not find profile %s referenced on line %d", nameNode.Value, nameNode.Line)
+		}
+		if profile.Kind != yaml.MappingNode {
+			return nil, fmt.Errorf("profile %s must be a map of router config", nameNode.Value)
+		}
+		resolved = mergeNodes(resolved, profile)
+	}
+
+	return mergeNodes(resolved, routerNode), nil
+}
+
+// mergeNodes deep merges override on top of base, returning a new node and leaving both inputs untouched
+func mergeNodes(base, override *yaml.Node) *yaml.Node {
+	if base == nil || base.Kind != override.Kind {
+		return cloneNode(override)
+	}
+
+	switch override.Kind {
+	case yaml.MappingNode:
+		merged := cloneNode(base)
+		for i := 0; i+1 < len(override.Content); i += 2 {
+			key := override.Content[i]
+			value := override.Content[i+1]
+
+			existing := -1
+			for j := 0; j+1 < len(merged.Content); j += 2 {
+				if merged.Content[j].Value == key.Value {
+					existing = j
+					break
+				}
+			}
+
+			if existing == -1 {
+				merged.Content = append(merged.Content, cloneNode(key), cloneNode(value))
+			} else {
+				merged.Content[existing+1] = mergeNodes(merged.Content[existing+1], value)
+			}
+		}
+		return merged
+	case yaml.SequenceNode:
+		if override.Tag == replaceTag {
+			return cloneNode(override)
+		}
+		merged := cloneNode(base)
+		for _, item := range override.Content {
+			merged.Content = append(merged.Content, cloneNode(item))
+		}
+		return merged
+	default:
+		return cloneNode(override)
+	}
+}
+
+func cloneNode(node *yaml.Node) *yaml.Node {
+	if node == nil {
+		return nil
+	}
+	clone := *node
+	clone.Content = make([]*yaml.Node, len(node.Content))
+	for i, child := range node.Content {
+		clone.Content[i] = cloneNode(child)
+	}
+	return &clone
+}
+
+// clearReplaceTags removes our custom merge tag so the decoder resolves the list normally
+func clearReplaceTags(node *yaml.Node) {
+	if node.Tag == replaceTag {
+		node.Tag = ""
+	}
+	for _, child := range node.Content {
+		clearReplaceTags(child)
+	}
+}
+
+// documentRoot returns the top level mapping of a parsed yaml document
+func documentRoot(doc *yaml.Node) *yaml.Node {
+	if doc.Kind == yaml.DocumentNode {
+		if len(doc.Content) == 0 {
+			return nil
+		}
+		doc = doc.Content[0]
+	}
+	if doc.Kind != yaml.MappingNode {
+		return nil
+	}
+	return doc
+}
+
+// mappingValue returns the value node for the given key in a mapping node, or nil if not present
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
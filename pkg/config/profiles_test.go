@@ -0,0 +1,212 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestResolveProfiles(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "router scalars win",
+			config: `
+profiles:
+  base:
+    username: admin
+    port: 22
+routers:
+  - name: router01
+    profiles: [base]
+    port: 2222
+`,
+			want: `
+- name: router01
+  username: admin
+  port: 2222
+  profiles: [base]
+`,
+		},
+		{
+			name: "lists append in profile order without replace",
+			config: `
+profiles:
+  dns:
+    nameservers: [1.1.1.1]
+  more-dns:
+    nameservers: [8.8.8.8]
+routers:
+  - name: router01
+    profiles: [dns, more-dns]
+    nameservers: [9.9.9.9]
+`,
+			want: `
+- name: router01
+  profiles: [dns, more-dns]
+  nameservers: [1.1.1.1, 8.8.8.8, 9.9.9.9]
+`,
+		},
+		{
+			name: "replace discards inherited list entries",
+			config: `
+profiles:
+  dns:
+    nameservers: [1.1.1.1, 8.8.8.8]
+routers:
+  - name: router01
+    profiles: [dns]
+    nameservers: !replace [9.9.9.9]
+`,
+			want: `
+- name: router01
+  profiles: [dns]
+  nameservers: [9.9.9.9]
+`,
+		},
+		{
+			name: "replace in a later profile discards earlier profiles",
+			config: `
+profiles:
+  dns:
+    nameservers: [1.1.1.1]
+  override:
+    nameservers: !replace [8.8.8.8]
+routers:
+  - name: router01
+    profiles: [dns, override]
+    nameservers: [9.9.9.9]
+`,
+			want: `
+- name: router01
+  profiles: [dns, override]
+  nameservers: [8.8.8.8, 9.9.9.9]
+`,
+		},
+		{
+			name: "nested maps merge key by key",
+			config: `
+profiles:
+  base:
+    connection:
+      username: admin
+      port: 22
+    firewall:
+      options:
+        syn-cookies: true
+        log-martians: true
+routers:
+  - name: router01
+    profiles: [base]
+    connection:
+      ip: 192.0.2.1
+    firewall:
+      options:
+        log-martians: false
+`,
+			want: `
+- name: router01
+  profiles: [base]
+  connection:
+    username: admin
+    port: 22
+    ip: 192.0.2.1
+  firewall:
+    options:
+      syn-cookies: true
+      log-martians: false
+`,
+		},
+		{
+			name: "profiles are not changed by the routers using them",
+			config: `
+profiles:
+  dns:
+    nameservers: [1.1.1.1]
+routers:
+  - name: router01
+    profiles: [dns]
+    nameservers: [8.8.8.8]
+  - name: router02
+    profiles: [dns]
+`,
+			want: `
+- name: router01
+  profiles: [dns]
+  nameservers: [1.1.1.1, 8.8.8.8]
+- name: router02
+  profiles: [dns]
+  nameservers: [1.1.1.1]
+`,
+		},
+		{
+			name: "routers without profiles are untouched",
+			config: `
+routers:
+  - name: router01
+    nameservers: !replace [8.8.8.8]
+`,
+			want: `
+- name: router01
+  nameservers: [8.8.8.8]
+`,
+		},
+		{
+			name: "unknown profile",
+			config: `
+routers:
+  - name: router01
+    profiles: [missing]
+`,
+			wantErr: true,
+		},
+		{
+			name: "profiles must be a list",
+			config: `
+profiles:
+  base:
+    username: admin
+routers:
+  - name: router01
+    profiles: base
+`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var doc yaml.Node
+			if err := yaml.Unmarshal([]byte(tt.config), &doc); err != nil {
+				t.Fatal(err)
+			}
+
+			err := resolveProfiles(&doc)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("resolveProfiles() expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolveProfiles() returned error: %v", err)
+			}
+
+			var got, want interface{}
+			if err := mappingValue(documentRoot(&doc), "routers").Decode(&got); err != nil {
+				t.Fatal(err)
+			}
+			if err := yaml.Unmarshal([]byte(tt.want), &want); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("resolved routers =\n%v\nwant\n%v", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,257 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"net/netip"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+// templateFuncs are the helper functions available to templates in the config
+var templateFuncs = template.FuncMap{
+	"add":        templateAdd,
+	"cidrhost":   cidrHost,
+	"cidrsubnet": cidrSubnet,
+}
+
+// renderTemplates executes go templates found in string values of the config
+// Router values are rendered with the global vars overlaid with the router's own vars (including vars from its profiles)
+// Everything else is rendered with only the global vars
+func renderTemplates(doc *yaml.Node) error {
+	root := documentRoot(doc)
+	if root == nil {
+		return nil
+	}
+
+	globalVars, err := decodeVars(root)
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key := root.Content[i].Value
+		value := root.Content[i+1]
+
+		switch key {
+		case "vars", "profiles":
+			// Profiles have already been merged into routers, and vars are not templated themselves
+			continue
+		case "routers":
+			if value.Kind != yaml.SequenceNode {
+				continue
+			}
+			for _, routerNode := range value.Content {
+				routerVars, err := decodeVars(routerNode)
+				if err != nil {
+					return err
+				}
+				vars := map[string]interface{}{}
+				for k, v := range globalVars {
+					vars[k] = v
+				}
+				for k, v := range routerVars {
+					vars[k] = v
+				}
+				err = renderNode(routerNode, vars)
+				if err != nil {
+					return err
+				}
+			}
+		default:
+			err = renderNode(value, globalVars)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// decodeVars decodes the vars block from a mapping node, if one exists
+func decodeVars(node *yaml.Node) (map[string]interface{}, error) {
+	vars := map[string]interface{}{}
+	varsNode := mappingValue(node, "vars")
+	if varsNode == nil {
+		return vars, nil
+	}
+
+	err := varsNode.Decode(&vars)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding vars on line %d: %w", varsNode.Line, err)
+	}
+
+	return vars, nil
+}
+
+// renderNode walks the node and executes any scalar (keys or values) that contains a template
+func renderNode(node *yaml.Node, vars map[string]interface{}) error {
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == "vars" {
+				continue
+			}
+			if err := renderNode(node.Content[i], vars); err != nil {
+				return err
+			}
+			if err := renderNode(node.Content[i+1], vars); err != nil {
+				return err
+			}
+		}
+	case yaml.SequenceNode:
+		for _, child := range node.Content {
+			if err := renderNode(child, vars); err != nil {
+				return err
+			}
+		}
+	case yaml.ScalarNode:
+		if !strings.Contains(node.Value, "{{") {
+			return nil
+		}
+
+		tmpl, err := template.New("value").Option("missingkey=error").Funcs(templateFuncs).Parse(node.Value)
+		if err != nil {
+			return fmt.Errorf("error parsing template on line %d: %w", node.Line, err)
+		}
+
+		var rendered bytes.Buffer
+		err = tmpl.Execute(&rendered, vars)
+		if err != nil {
+			return fmt.Errorf("error rendering template on line %d: %w", node.Line, err)
+		}
+
+		// Templates have to be quoted in yaml, so reset the tag and style to let the decoder
+		// resolve the rendered value to whatever type the field expects
+		node.Value = rendered.String()
+		node.Tag = ""
+		node.Style = 0
+	}
+
+	return nil
+}
+
+// templateAdd adds integers together, accepting anything that looks like an integer
+func templateAdd(values ...interface{}) (int64, error) {
+	var sum int64
+	for _, value := range values {
+		intVal, err := toInt64(value)
+		if err != nil {
+			return 0, err
+		}
+		sum += intVal
+	}
+	return sum, nil
+}
+
+// cidrHost returns the address of the given host number within the prefix
+// Negative host numbers count back from the end of the prefix
+func cidrHost(prefixVal interface{}, hostNumVal interface{}) (string, error) {
+	prefix, err := netip.ParsePrefix(fmt.Sprintf("%v", prefixVal))
+	if err != nil {
+		return "", fmt.Errorf("cidrhost: %w", err)
+	}
+	hostNum, err := toInt64(hostNumVal)
+	if err != nil {
+		return "", fmt.Errorf("cidrhost: %w", err)
+	}
+
+	prefix = prefix.Masked()
+	hostBits := uint(prefix.Addr().BitLen() - prefix.Bits())
+	size := new(big.Int).Lsh(big.NewInt(1), hostBits)
+
+	offset := big.NewInt(hostNum)
+	if hostNum < 0 {
+		offset.Add(size, offset)
+	}
+	if offset.Sign() < 0 || offset.Cmp(size) >= 0 {
+		return "", fmt.Errorf("cidrhost: host number %d does not fit in %s", hostNum, prefix.String())
+	}
+
+	addr, err := addrAdd(prefix.Addr(), offset)
+	if err != nil {
+		return "", fmt.Errorf("cidrhost: %w", err)
+	}
+	return addr.String(), nil
+}
+
+// cidrSubnet extends the prefix by newBits and returns the netNum'th subnet of that size
+func cidrSubnet(prefixVal interface{}, newBitsVal interface{}, netNumVal interface{}) (string, error) {
+	prefix, err := netip.ParsePrefix(fmt.Sprintf("%v", prefixVal))
+	if err != nil {
+		return "", fmt.Errorf("cidrsubnet: %w", err)
+	}
+	newBits, err := toInt64(newBitsVal)
+	if err != nil {
+		return "", fmt.Errorf("cidrsubnet: %w", err)
+	}
+	netNum, err := toInt64(netNumVal)
+	if err != nil {
+		return "", fmt.Errorf("cidrsubnet: %w", err)
+	}
+
+	prefix = prefix.Masked()
+	newLen := int64(prefix.Bits()) + newBits
+	if newBits < 0 || newLen > int64(prefix.Addr().BitLen()) {
+		return "", fmt.Errorf("cidrsubnet: cannot extend %s by %d bits", prefix.String(), newBits)
+	}
+	if netNum < 0 || big.NewInt(netNum).Cmp(new(big.Int).Lsh(big.NewInt(1), uint(newBits))) >= 0 {
+		return "", fmt.Errorf("cidrsubnet: network number %d does not fit in %d bits", netNum, newBits)
+	}
+
+	offset := new(big.Int).Lsh(big.NewInt(netNum), uint(int64(prefix.Addr().BitLen())-newLen))
+	addr, err := addrAdd(prefix.Addr(), offset)
+	if err != nil {
+		return "", fmt.Errorf("cidrsubnet: %w", err)
+	}
+
+	return netip.PrefixFrom(addr, int(newLen)).String(), nil
+}
+
+// addrAdd adds offset to the address
+func addrAdd(addr netip.Addr, offset *big.Int) (netip.Addr, error) {
+	value := new(big.Int).SetBytes(addr.AsSlice())
+	value.Add(value, offset)
+
+	buf := make([]byte, len(addr.AsSlice()))
+	if value.BitLen() > len(buf)*8 {
+		return netip.Addr{}, fmt.Errorf("address overflow adding %s to %s", offset.String(), addr.String())
+	}
+	value.FillBytes(buf)
+
+	result, ok := netip.AddrFromSlice(buf)
+	if !ok {
+		return netip.Addr{}, fmt.Errorf("invalid address adding %s to %s", offset.String(), addr.String())
+	}
+	return result, nil
+}
+
+func toInt64(value interface{}) (int64, error) {
+	switch v := value.(type) {
+	case int:
+		return int64(v), nil
+	case int64:
+		return v, nil
+	case int32:
+		return int64(v), nil
+	case uint:
+		return int64(v), nil
+	case uint8:
+		return int64(v), nil
+	case uint16:
+		return int64(v), nil
+	case uint32:
+		return int64(v), nil
+	case uint64:
+		return int64(v), nil
+	case string:
+		return strconv.ParseInt(v, 10, 64)
+	default:
+		return 0, fmt.Errorf("expected an integer, got %T", value)
+	}
+}
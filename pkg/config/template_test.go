@@ -0,0 +1,185 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestTemplateAdd(t *testing.T) {
+	tests := []struct {
+		name    string
+		values  []interface{}
+		want    int64
+		wantErr bool
+	}{
+		{name: "no values", want: 0},
+		{name: "ints", values: []interface{}{1, 2, 3}, want: 6},
+		{name: "mixed integer types", values: []interface{}{int64(100), uint8(5), "10"}, want: 115},
+		{name: "negative", values: []interface{}{10, -20}, want: -10},
+		{name: "not an integer", values: []interface{}{1, "two"}, wantErr: true},
+		{name: "float", values: []interface{}{1.5}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := templateAdd(tt.values...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("add(%v) error = %v, wantErr %t", tt.values, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("add(%v) = %d, want %d", tt.values, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCIDRHost(t *testing.T) {
+	tests := []struct {
+		name    string
+		prefix  interface{}
+		hostNum interface{}
+		want    string
+		wantErr bool
+	}{
+		{name: "first host", prefix: "192.0.2.0/24", hostNum: 1, want: "192.0.2.1"},
+		{name: "network address", prefix: "192.0.2.0/24", hostNum: 0, want: "192.0.2.0"},
+		{name: "unmasked prefix", prefix: "192.0.2.77/24", hostNum: 10, want: "192.0.2.10"},
+		{name: "negative counts from the end", prefix: "192.0.2.0/24", hostNum: -2, want: "192.0.2.254"},
+		{name: "string host number", prefix: "10.0.0.0/16", hostNum: "300", want: "10.0.1.44"},
+		{name: "ipv6", prefix: "2001:db8::/64", hostNum: 1, want: "2001:db8::1"},
+		{name: "last host", prefix: "192.0.2.0/24", hostNum: 255, want: "192.0.2.255"},
+		{name: "out of range", prefix: "192.0.2.0/24", hostNum: 256, wantErr: true},
+		{name: "negative out of range", prefix: "192.0.2.0/24", hostNum: -257, wantErr: true},
+		{name: "out of range in a /32", prefix: "192.0.2.1/32", hostNum: 1, wantErr: true},
+		{name: "invalid prefix", prefix: "192.0.2.0", hostNum: 1, wantErr: true},
+		{name: "invalid host number", prefix: "192.0.2.0/24", hostNum: "one", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := cidrHost(tt.prefix, tt.hostNum)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("cidrhost(%v, %v) error = %v, wantErr %t", tt.prefix, tt.hostNum, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("cidrhost(%v, %v) = %q, want %q", tt.prefix, tt.hostNum, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCIDRSubnet(t *testing.T) {
+	tests := []struct {
+		name    string
+		prefix  interface{}
+		newBits interface{}
+		netNum  interface{}
+		want    string
+		wantErr bool
+	}{
+		{name: "first subnet", prefix: "10.0.0.0/16", newBits: 8, netNum: 0, want: "10.0.0.0/24"},
+		{name: "numbered subnet", prefix: "10.0.0.0/16", newBits: 8, netNum: 100, want: "10.0.100.0/24"},
+		{name: "no new bits", prefix: "10.0.0.0/16", newBits: 0, netNum: 0, want: "10.0.0.0/16"},
+		{name: "unmasked prefix", prefix: "10.0.55.1/16", newBits: 4, netNum: 1, want: "10.0.16.0/20"},
+		{name: "ipv6", prefix: "2001:db8::/48", newBits: 16, netNum: 10, want: "2001:db8:0:a::/64"},
+		{name: "network number out of range", prefix: "10.0.0.0/16", newBits: 8, netNum: 256, wantErr: true},
+		{name: "negative network number", prefix: "10.0.0.0/16", newBits: 8, netNum: -1, wantErr: true},
+		{name: "prefix too long", prefix: "10.0.0.0/30", newBits: 3, netNum: 0, wantErr: true},
+		{name: "negative new bits", prefix: "10.0.0.0/16", newBits: -1, netNum: 0, wantErr: true},
+		{name: "invalid prefix", prefix: "10.0.0.0", newBits: 8, netNum: 0, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := cidrSubnet(tt.prefix, tt.newBits, tt.netNum)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("cidrsubnet(%v, %v, %v) error = %v, wantErr %t", tt.prefix, tt.newBits, tt.netNum, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("cidrsubnet(%v, %v, %v) = %q, want %q", tt.prefix, tt.newBits, tt.netNum, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderTemplates(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "router vars override global vars",
+			config: `
+vars:
+  site: 10
+  prefix: 10.0.0.0/16
+routers:
+  - name: router01
+    vars:
+      site: 20
+    address: '{{ cidrhost (cidrsubnet .prefix 8 .site) 1 }}/24'
+    asn: '{{ add 65000 .site }}'
+`,
+			want: `
+- name: router01
+  vars:
+    site: 20
+  address: 10.0.20.1/24
+  asn: 65020
+`,
+		},
+		{
+			name: "missing vars are an error",
+			config: `
+routers:
+  - name: router01
+    address: '{{ .missing }}'
+`,
+			wantErr: true,
+		},
+		{
+			name: "function errors are returned",
+			config: `
+routers:
+  - name: router01
+    address: '{{ cidrhost "192.0.2.0/24" 300 }}'
+`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var doc yaml.Node
+			if err := yaml.Unmarshal([]byte(tt.config), &doc); err != nil {
+				t.Fatal(err)
+			}
+
+			err := renderTemplates(&doc)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("renderTemplates() expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("renderTemplates() returned error: %v", err)
+			}
+
+			var got, want interface{}
+			if err := mappingValue(documentRoot(&doc), "routers").Decode(&got); err != nil {
+				t.Fatal(err)
+			}
+			if err := yaml.Unmarshal([]byte(tt.want), &want); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("rendered routers =\n%v\nwant\n%v", got, want)
+			}
+		})
+	}
+}
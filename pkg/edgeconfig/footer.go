@@ -0,0 +1,131 @@
+package edgeconfig
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// TestedComponents are the config component versions edgefig has been tested against
+// These come from the vyatta-config-version footer of the default configs for EdgeOS v2.0.9
+var TestedComponents = map[string]int{
+	"config-management": 1,
+	"conntrack":         1,
+	"cron":              1,
+	"dhcp-relay":        1,
+	"dhcp-server":       4,
+	"firewall":          5,
+	"ipsec":             5,
+	"nat":               3,
+	"qos":               1,
+	"quagga":            2,
+	"suspend":           1,
+	"system":            5,
+	"ubnt-l2tp":         1,
+	"ubnt-pptp":         1,
+	"ubnt-udapi-server": 1,
+	"ubnt-unms":         2,
+	"ubnt-util":         1,
+	"vrrp":              1,
+	"vyatta-netflow":    1,
+	"webgui":            1,
+	"webproxy":          1,
+	"zone-policy":       1,
+}
+
+var (
+	versionLineRegex = regexp.MustCompile(`/\* === vyatta-config-version: "([^"]*)" === \*/`)
+	releaseLineRegex = regexp.MustCompile(`/\* Release version: (.*?) \*/`)
+)
+
+// Footer is the version information at the end of a config.boot file
+// The router uses it to migrate the config between firmware versions, so it must be present when loading a config
+type Footer struct {
+	// Components is the raw component list, such as "config-management@1:conntrack@1:..."
+	Components string
+	// Release is the firmware release that wrote the config, if present
+	Release string
+}
+
+// ParseFooter finds the version footer in a config.boot file, regardless of where it is or what surrounds it
+func ParseFooter(config []byte) (Footer, error) {
+	versionMatch := versionLineRegex.FindSubmatch(config)
+	if versionMatch == nil {
+		return Footer{}, fmt.Errorf("config does not contain a vyatta-config-version footer")
+	}
+
+	footer := Footer{Components: string(versionMatch[1])}
+	if releaseMatch := releaseLineRegex.FindSubmatch(config); releaseMatch != nil {
+		footer.Release = string(releaseMatch[1])
+	}
+
+	return footer, footer.Validate()
+}
+
+// Validate ensures the footer has a parseable, non-empty component list
+func (f Footer) Validate() error {
+	components, err := f.ComponentVersions()
+	if err != nil {
+		return err
+	}
+	if len(components) == 0 {
+		return fmt.Errorf("config version footer has no components")
+	}
+	return nil
+}
+
+// ComponentVersions parses the component list into a map of component name to version
+func (f Footer) ComponentVersions() (map[string]int, error) {
+	components := map[string]int{}
+	for _, component := range strings.Split(f.Components, ":") {
+		if component == "" {
+			continue
+		}
+		name, versionStr, ok := strings.Cut(component, "@")
+		if !ok {
+			return nil, fmt.Errorf("invalid component %s in config version footer", component)
+		}
+		version, err := strconv.Atoi(versionStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid version for component %s in config version footer", name)
+		}
+		components[name] = version
+	}
+	return components, nil
+}
+
+// UntestedComponents returns a description of every component whose version differs from TestedComponents
+func (f Footer) UntestedComponents() []string {
+	components, err := f.ComponentVersions()
+	if err != nil {
+		return nil
+	}
+
+	var untested []string
+	for name, version := range components {
+		tested, ok := TestedComponents[name]
+		if !ok {
+			untested = append(untested, fmt.Sprintf("%s@%d (not tested)", name, version))
+			continue
+		}
+		if tested != version {
+			untested = append(untested, fmt.Sprintf("%s@%d (tested with @%d)", name, version, tested))
+		}
+	}
+	sort.Strings(untested)
+
+	return untested
+}
+
+// Marshal returns the footer in the format expected at the end of config.boot
+func (f Footer) Marshal() []byte {
+	var b strings.Builder
+	b.WriteString("\n\n/* Warning: Do not remove the following line. */\n")
+	b.WriteString(fmt.Sprintf("/* === vyatta-config-version: \"%s\" === */\n", f.Components))
+	if f.Release != "" {
+		b.WriteString(fmt.Sprintf("/* Release version: %s */\n", f.Release))
+	}
+	return []byte(b.String())
+}
@@ -3,7 +3,7 @@ package edgeconfig
 import (
 	"bytes"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/netip"
 	"reflect"
 	"strings"
@@ -63,11 +63,19 @@ func marshalValue(buffer *bytes.Buffer, val reflect.Value, depth int) error {
 			if inline {
 				err := marshalValue(buffer, field, depth)
 				if err != nil {
-					log.Printf("[ERROR] %s\n", err.Error())
+					slog.Error("error marshalling inline field", "error", err.Error())
 				}
 				continue
 			}
 
+			// Optional sections are pointers, and are left out entirely when nil
+			if field.Kind() == reflect.Ptr {
+				if field.IsNil() {
+					continue
+				}
+				field = field.Elem()
+			}
+
 			// Handle a few specific type edge cases
 			specificType := field.Type().String()
 			switch specificType {
@@ -129,6 +137,18 @@ func marshalValue(buffer *bytes.Buffer, val reflect.Value, depth int) error {
 					}
 					bufferWriteHelper(buffer, strings.Repeat(" ", depth)+"}\n")
 				case reflect.Slice:
+					// Custom marshalled slices (like port lists) are a single value rather than a repeated key
+					if _, ok := field.Interface().(EdgeMarshaller); ok {
+						if omitEmpty && field.Len() == 0 {
+							continue
+						}
+						fieldValue, err := formatValue(field, omitEmpty)
+						if err != nil {
+							return err
+						}
+						bufferWriteHelper(buffer, fmt.Sprintf("%s%s%s\n", strings.Repeat(" ", depth), tag, fieldValue))
+						continue
+					}
 					for i := 0; i < field.Len(); i++ {
 						sliceElement := field.Index(i)
 
@@ -138,9 +158,11 @@ func marshalValue(buffer *bytes.Buffer, val reflect.Value, depth int) error {
 						}
 						bufferWriteHelper(buffer, fmt.Sprintf("%s%s", strings.Repeat(" ", depth), tag))
 
+						// Slices of scalars (including custom marshalled scalars) are repeated leaves, anything else is a block
 						typeStr := sliceElement.Type().String()
-						switch typeStr {
-						case "netip.Prefix", "netip.Addr", "string":
+						_, isMarshaller := sliceElement.Interface().(EdgeMarshaller)
+						switch {
+						case isMarshaller, sliceElement.Kind() == reflect.String, typeStr == "netip.Prefix", typeStr == "netip.Addr":
 							val, err := formatValue(sliceElement, omitEmpty)
 							if err != nil {
 								return err
@@ -0,0 +1,121 @@
+package edgeconfig
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Platform is what the router reports about itself, used to leave out or refuse config it doesn't support
+type Platform struct {
+	// Components are the config components from the live config's version footer, such as "zone-policy": 1
+	// The firmware only lists components it supports, so a missing component means the feature isn't available
+	Components map[string]int
+	// Model is the hardware model from `show version`, such as "EdgeRouter X SFP 6-port"
+	Model string
+}
+
+// platformFeature is a config node that is only supported on some routers
+type platformFeature struct {
+	name string
+	// requires describes what the router needs for the feature, for error messages
+	requires string
+	// present checks if the feature is used in the config
+	present func(rc *Router) bool
+	// supported checks if the router supports the feature
+	supported func(platform Platform) bool
+	// adapt removes the feature from the config. If nil, the config is refused instead
+	adapt func(rc *Router)
+}
+
+// platformFeatures lists the config nodes that depend on what the router supports
+// Sections that edgefig adds by default are adapted (removed) when unsupported, anything else is refused
+var platformFeatures = []platformFeature{
+	{
+		name:      "service unms",
+		requires:  "the ubnt-unms config component",
+		present:   func(rc *Router) bool { return rc.Service.UNMS != nil },
+		supported: hasComponent("ubnt-unms"),
+		adapt:     func(rc *Router) { rc.Service.UNMS = nil },
+	},
+	{
+		name:      "zone-policy",
+		requires:  "the zone-policy config component",
+		present:   func(rc *Router) bool { return len(rc.ZonePolicy.Zones) > 0 },
+		supported: hasComponent("zone-policy"),
+	},
+	{
+		name:      "system offload hwnat",
+		requires:  "a MediaTek based router, such as the EdgeRouter X",
+		present:   func(rc *Router) bool { return rc.System.Offload != nil && rc.System.Offload.HWNAT != nil },
+		supported: func(platform Platform) bool { return !hasModelPrefix(platform.Model, caviumModels) },
+	},
+	{
+		name:     "system offload ipv4",
+		requires: "a Cavium based router, such as the EdgeRouter 4",
+		present:  func(rc *Router) bool { return rc.System.Offload != nil && rc.System.Offload.IPv4 != nil },
+		supported: func(platform Platform) bool {
+			return !hasModelPrefix(platform.Model, mediaTekModels)
+		},
+	},
+	{
+		name:     "system offload ipv6",
+		requires: "a Cavium based router, such as the EdgeRouter 4",
+		present:  func(rc *Router) bool { return rc.System.Offload != nil && rc.System.Offload.IPv6 != nil },
+		supported: func(platform Platform) bool {
+			return !hasModelPrefix(platform.Model, mediaTekModels)
+		},
+	},
+}
+
+// Hardware models from `show version`, grouped by the chip that does their offloading
+// MediaTek based routers offload with hwnat, Cavium based routers with ipv4/ipv6 forwarding
+var (
+	mediaTekModels = []string{"EdgeRouter X", "EdgeRouter 10X", "EdgePoint R6"}
+	caviumModels   = []string{
+		"EdgeRouter Lite", "EdgeRouter PoE", "EdgeRouter 4", "EdgeRouter 6P", "EdgeRouter 8",
+		"EdgeRouter 12", "EdgeRouter Pro", "EdgeRouter Infinity", "EdgePoint R8",
+	}
+)
+
+// hasModelPrefix returns whether the model starts with any of the prefixes
+func hasModelPrefix(model string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(model, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasComponent returns a check for the config component, which passes when the components aren't known
+func hasComponent(name string) func(platform Platform) bool {
+	return func(platform Platform) bool {
+		if len(platform.Components) == 0 {
+			return true
+		}
+		_, ok := platform.Components[name]
+		return ok
+	}
+}
+
+// AdaptToPlatform removes or refuses config that the router doesn't support
+// Returns the names of any features that were removed, or an error listing features that can't be removed
+// Anything the router doesn't report (no components, or an unknown model) is assumed to be supported
+func (rc *Router) AdaptToPlatform(platform Platform) ([]string, error) {
+	var adapted []string
+	var errs []error
+	for _, feature := range platformFeatures {
+		if !feature.present(rc) || feature.supported(platform) {
+			continue
+		}
+		if feature.adapt == nil {
+			errs = append(errs, fmt.Errorf("%s needs %s, which the router doesn't support", feature.name, feature.requires))
+			continue
+		}
+		feature.adapt(rc)
+		adapted = append(adapted, feature.name)
+	}
+
+	return adapted, errors.Join(errs...)
+}
@@ -0,0 +1,109 @@
+package edgeconfig
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/cmmarslender/edgefig/pkg/types"
+)
+
+func TestAdaptToPlatform(t *testing.T) {
+	// Components from the footer of an EdgeOS v2.0.9 config
+	current := map[string]int{"config-management": 1, "firewall": 5, "system": 5, "ubnt-unms": 2, "zone-policy": 1}
+	// An older firmware that predates unms and doesn't have zone-policy
+	older := map[string]int{"config-management": 1, "firewall": 4, "system": 4}
+	enable := types.Enable
+
+	tests := []struct {
+		name     string
+		platform Platform
+		router   func() *Router
+		adapted  []string
+		wantErr  bool
+	}{
+		{
+			name:     "default sections are removed when the component is missing",
+			platform: Platform{Components: older},
+			router: func() *Router {
+				return &Router{Service: RouterServices{UNMS: &UNMSService{}}}
+			},
+			adapted: []string{"service unms"},
+		},
+		{
+			name:     "default sections are kept when the component is listed",
+			platform: Platform{Components: current},
+			router: func() *Router {
+				return &Router{Service: RouterServices{UNMS: &UNMSService{}}}
+			},
+		},
+		{
+			name:     "zone policy is refused when the component is missing",
+			platform: Platform{Components: older},
+			router: func() *Router {
+				return &Router{ZonePolicy: ZonePolicy{Zones: []PolicyZone{{Name: "lan"}}}}
+			},
+			wantErr: true,
+		},
+		{
+			name:     "hwnat offload is refused on cavium routers",
+			platform: Platform{Model: "EdgeRouter 4"},
+			router: func() *Router {
+				return &Router{System: RouterSystem{Offload: &Offload{HWNAT: &enable}}}
+			},
+			wantErr: true,
+		},
+		{
+			name:     "hwnat offload is allowed on mediatek routers",
+			platform: Platform{Model: "EdgeRouter X SFP 6-port"},
+			router: func() *Router {
+				return &Router{System: RouterSystem{Offload: &Offload{HWNAT: &enable, IPSec: &enable}}}
+			},
+		},
+		{
+			name:     "forwarding offload is refused on mediatek routers",
+			platform: Platform{Model: "EdgeRouter X 5-Port"},
+			router: func() *Router {
+				return &Router{System: RouterSystem{Offload: &Offload{IPv4: &OffloadFamily{Forwarding: &enable}}}}
+			},
+			wantErr: true,
+		},
+		{
+			name:     "forwarding offload is allowed on cavium routers",
+			platform: Platform{Model: "EdgeRouter Infinity"},
+			router: func() *Router {
+				return &Router{System: RouterSystem{Offload: &Offload{
+					IPv4: &OffloadFamily{Forwarding: &enable, VLAN: &enable},
+					IPv6: &OffloadFamily{Forwarding: &enable},
+				}}}
+			},
+		},
+		{
+			name:     "unknown components allow everything",
+			platform: Platform{},
+			router: func() *Router {
+				return &Router{
+					Service:    RouterServices{UNMS: &UNMSService{}},
+					ZonePolicy: ZonePolicy{Zones: []PolicyZone{{Name: "lan"}}},
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			adapted, err := tt.router().AdaptToPlatform(tt.platform)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("AdaptToPlatform() expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("AdaptToPlatform() returned error: %v", err)
+			}
+			if !slices.Equal(adapted, tt.adapted) {
+				t.Errorf("AdaptToPlatform() adapted %v, want %v", adapted, tt.adapted)
+			}
+		})
+	}
+}
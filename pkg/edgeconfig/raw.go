@@ -0,0 +1,171 @@
+package edgeconfig
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MergeRaw merges a snippet of hand written config into the tree
+// The snippet is either config.boot syntax, or a list of EdgeOS `set` commands (one per line)
+// Anything that would change a value the tree already has is returned as an error, rather than silently overriding it
+func (n *Node) MergeRaw(snippet string) error {
+	var lines []string
+	for _, line := range strings.Split(snippet, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+
+	isSet := len(lines) > 0
+	for _, line := range lines {
+		if !strings.HasPrefix(line, "set ") {
+			isSet = false
+			break
+		}
+	}
+
+	if !isSet {
+		raw, err := ParseTree([]byte(snippet))
+		if err != nil {
+			return err
+		}
+		return n.merge("", raw)
+	}
+
+	for _, line := range lines {
+		words := splitWords(strings.TrimPrefix(line, "set "))
+		if len(words) == 0 {
+			return fmt.Errorf("empty set command")
+		}
+		if err := n.mergePath("", words); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// tagNodes are the EdgeOS nodes that take a name or value and hold a block, like "rule 10 { ... }"
+var tagNodes = map[string]bool{
+	"address-group": true, "ethernet": true, "from": true, "interface": true, "ipv6-address-group": true,
+	"ipv6-name": true, "ipv6-network-group": true, "modify": true, "name": true, "neighbor": true,
+	"network-group": true, "next-hop": true, "port-group": true, "prefix-list": true, "prefix-list6": true,
+	"route": true, "route6": true, "route-map": true, "rule": true, "server": true, "shared-network-name": true,
+	"static-mapping": true, "subnet": true, "switch": true, "table": true, "task": true, "user": true, "vif": true,
+	"zone": true,
+}
+
+// multiValueNodes are the EdgeOS leaves that can be set more than once, like several "name-server" leaves
+var multiValueNodes = map[string]bool{
+	"address": true, "dns-server": true, "domain-search": true, "interface": true, "ipv6-address": true,
+	"ipv6-network": true, "listen-on": true, "member": true, "module": true, "name-server": true, "network": true,
+	"port": true,
+}
+
+// mergePath merges a single `set` path into the tree
+// The path follows existing nodes as far as it can. Whatever is left becomes new blocks, where a tag node followed by
+// more than its value becomes a "key value" block (rule 10 { ... }) and other words become single word blocks.
+// The last two words are a "key value" leaf
+func (n *Node) mergePath(path string, words []string) error {
+	for _, child := range n.Children {
+		childWords := splitWords(child.Name)
+		if tokensEqual(childWords, words) {
+			// Already set
+			return nil
+		}
+		if child.Block && len(words) > len(childWords) && tokensEqual(words[:len(childWords)], childWords) {
+			return child.mergePath(strings.TrimSpace(path+" "+child.Name), words[len(childWords):])
+		}
+	}
+
+	raw := &Node{Block: true}
+	parent := raw
+	for len(words) > 2 {
+		size := 1
+		if tagNodes[words[0]] {
+			size = 2
+		}
+		block := &Node{Name: strings.Join(words[:size], " "), Block: true}
+		parent.Children = append(parent.Children, block)
+		parent = block
+		words = words[size:]
+	}
+	parent.Children = append(parent.Children, &Node{Name: strings.Join(words, " ")})
+
+	return n.merge(path, raw)
+}
+
+// merge adds everything in raw to the tree, recursing into blocks that exist in both
+func (n *Node) merge(path string, raw *Node) error {
+	for _, rawChild := range raw.Children {
+		childPath := strings.TrimSpace(path + " " + rawChild.Name)
+		existing := n.Child(rawChild.Name)
+
+		if existing != nil {
+			if existing.Block != rawChild.Block {
+				return fmt.Errorf("raw config %s conflicts with generated config at the same path", childPath)
+			}
+			if existing.Block {
+				if err := existing.merge(childPath, rawChild); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		for _, child := range n.Children {
+			if conflicts(child, rawChild) {
+				return fmt.Errorf("raw config %s conflicts with generated %s", childPath, strings.TrimSpace(path+" "+child.Name))
+			}
+		}
+
+		n.Children = append(n.Children, rawChild.Clone())
+	}
+
+	return nil
+}
+
+// conflicts checks whether two differently named siblings would set the same thing,
+// like "host-name a" and "host-name b", or a "host-name" block next to a "host-name a" leaf
+// Leaves that can be set more than once, like "name-server", don't conflict with each other
+func conflicts(generated, raw *Node) bool {
+	switch {
+	case !generated.Block && !raw.Block:
+		return leafKey(generated) == leafKey(raw) && !multiValueNodes[leafKey(raw)]
+	case !generated.Block:
+		return leafKey(generated) == raw.Name
+	case !raw.Block:
+		return generated.Name == leafKey(raw)
+	default:
+		return false
+	}
+}
+
+// splitWords splits a config path into words, keeping quoted values (like descriptions) together
+func splitWords(path string) []string {
+	var words []string
+	var current strings.Builder
+	inQuotes := false
+
+	for _, r := range path {
+		switch {
+		case r == '"':
+			inQuotes = !inQuotes
+			current.WriteRune(r)
+		case (r == ' ' || r == '\t') && !inQuotes:
+			if current.Len() > 0 {
+				words = append(words, current.String())
+				current.Reset()
+			}
+		default:
+			current.WriteRune(r)
+		}
+	}
+	if current.Len() > 0 {
+		words = append(words, current.String())
+	}
+
+	return words
+}
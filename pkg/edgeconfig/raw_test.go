@@ -0,0 +1,142 @@
+package edgeconfig
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMergeRaw(t *testing.T) {
+	generated := `firewall {
+    name WAN_IN {
+        default-action drop
+        rule 10 {
+            action accept
+        }
+    }
+}
+interfaces {
+    ethernet eth0 {
+        address 192.0.2.1/24
+        mtu 1500
+    }
+}
+system {
+    host-name router01
+    name-server 1.1.1.1
+}
+`
+
+	tests := []struct {
+		name    string
+		snippet string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:    "tag nodes keep their value on the same level",
+			snippet: "set firewall name EXTRA rule 10 action accept",
+			want: `firewall {
+    name WAN_IN {
+        default-action drop
+        rule 10 {
+            action accept
+        }
+    }
+    name EXTRA {
+        rule 10 {
+            action accept
+        }
+    }
+}
+`,
+		},
+		{
+			name:    "set path follows existing tag nodes",
+			snippet: "set firewall name WAN_IN rule 20 protocol tcp",
+			want: `firewall {
+    name WAN_IN {
+        default-action drop
+        rule 10 {
+            action accept
+        }
+        rule 20 {
+            protocol tcp
+        }
+    }
+}
+`,
+		},
+		{
+			name:    "plain words become single word blocks",
+			snippet: "set service upnp2 listen-on eth1",
+			want: `service {
+    upnp2 {
+        listen-on eth1
+    }
+}
+`,
+		},
+		{
+			name:    "multi value leaves are added next to generated ones",
+			snippet: "set system name-server 8.8.8.8\nset interfaces ethernet eth0 address 192.0.2.2/24",
+			want: `interfaces {
+    ethernet eth0 {
+        address 192.0.2.1/24
+        mtu 1500
+        address 192.0.2.2/24
+    }
+}
+system {
+    host-name router01
+    name-server 1.1.1.1
+    name-server 8.8.8.8
+}
+`,
+		},
+		{
+			name:    "single value leaf conflicts",
+			snippet: "set system host-name router02",
+			wantErr: true,
+		},
+		{
+			name:    "single value leaf conflicts inside a tag node",
+			snippet: "set interfaces ethernet eth0 mtu 9000",
+			wantErr: true,
+		},
+		{
+			name:    "config.boot snippet",
+			snippet: "system {\n    name-server 9.9.9.9\n}",
+			want: `system {
+    host-name router01
+    name-server 1.1.1.1
+    name-server 9.9.9.9
+}
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tree, err := ParseTree([]byte(generated))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = tree.MergeRaw(tt.snippet)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("MergeRaw(%q) expected an error", tt.snippet)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("MergeRaw(%q) returned error: %v", tt.snippet, err)
+			}
+
+			got := string(tree.Marshal())
+			if !strings.Contains(got, tt.want) {
+				t.Errorf("MergeRaw(%q) =\n%s\nwant it to contain\n%s", tt.snippet, got, tt.want)
+			}
+		})
+	}
+}
@@ -18,6 +18,28 @@ type Router struct {
 	Protocols  RouterProtocols `edge:"protocols,omitempty"`
 	Service    RouterServices  `edge:"service"`
 	System     RouterSystem    `edge:"system"`
+	ZonePolicy ZonePolicy      `edge:"zone-policy,omitempty"`
+}
+
+// ZonePolicy is the zone based firewall config
+type ZonePolicy struct {
+	Zones []PolicyZone `edge:"zone {{ .Name }}"`
+}
+
+// PolicyZone is a single zone in the zone policy
+type PolicyZone struct {
+	Name          string
+	DefaultAction string               `edge:"default-action"`
+	Description   string               `edge:"description,omitempty"`
+	From          []PolicyZoneFrom     `edge:"from {{ .Zone }}"`
+	Interfaces    []string             `edge:"interface,omitempty"`
+	LocalZone     types.KeyWhenEnabled `edge:"local-zone,omitempty"`
+}
+
+// PolicyZoneFrom is the rulesets applied to traffic coming from another zone
+type PolicyZoneFrom struct {
+	Zone     string
+	Firewall InterfaceFirewallZone `edge:"firewall"`
 }
 
 // RouterPolicy is the policy settings for the router
@@ -79,22 +101,78 @@ type Firewall struct {
 	LogMartians          types.EnableDisable `edge:"log-martians"`
 	ReceiveRedirects     types.EnableDisable `edge:"receive-redirects"`
 	SendRedirects        types.EnableDisable `edge:"send-redirects"`
-	SourceValidation     types.EnableDisable `edge:"source-validation"`
+	SourceValidation     string              `edge:"source-validation"`
 	SynCookies           types.EnableDisable `edge:"syn-cookies"`
 	Group                FirewallGroups      `edge:"group"`
+	Modify               []FirewallModify    `edge:"modify {{ .Name }}"`
 	Zones                []FirewallZone      `edge:"{{ .NamePrefix }}name {{ .Name }}"`
 }
 
+// FirewallModify is a modify ruleset, which changes how matching packets are handled instead of filtering them
+type FirewallModify struct {
+	Name        string
+	Description string       `edge:"description,omitempty"`
+	Rules       []ModifyRule `edge:"rule {{ .Number }}"`
+}
+
+// ModifyRule is a single rule within a modify ruleset
+type ModifyRule struct {
+	Number      uint32
+	Action      string            `edge:"action"`
+	Description string            `edge:"description,omitempty"`
+	Destination types.AddressPort `edge:"destination,omitempty"`
+	Modify      ModifyAction      `edge:"modify"`
+	Protocol    types.Protocol    `edge:"protocol,omitempty"`
+	Source      types.AddressPort `edge:"source,omitempty"`
+}
+
+// ModifyAction is the change made to packets matching a modify rule
+type ModifyAction struct {
+	Table uint8 `edge:"table"`
+}
+
 // FirewallGroups groups for the firewall
 type FirewallGroups struct {
-	AddressGroups []AddressGroup `edge:"address-group {{ .Name }}"`
+	AddressGroups     []AddressGroup     `edge:"address-group {{ .Name }}"`
+	IPv6AddressGroups []IPv6AddressGroup `edge:"ipv6-address-group {{ .Name }}"`
+	IPv6NetworkGroups []IPv6NetworkGroup `edge:"ipv6-network-group {{ .Name }}"`
+	NetworkGroups     []NetworkGroup     `edge:"network-group {{ .Name }}"`
+	PortGroups        []PortGroup        `edge:"port-group {{ .Name }}"`
 }
 
 // AddressGroup is a single address group in the edgeconfig
 type AddressGroup struct {
-	Name              string
-	types.AddressPort `edge:",inline"`
-	Description       string `edge:"description"`
+	Name        string
+	Addresses   []types.GroupAddress `edge:"address"`
+	Description string               `edge:"description,omitempty"`
+}
+
+// IPv6AddressGroup is a single ipv6 address group in the edgeconfig
+type IPv6AddressGroup struct {
+	Name        string
+	Description string               `edge:"description,omitempty"`
+	Addresses   []types.GroupAddress `edge:"ipv6-address"`
+}
+
+// NetworkGroup is a single network group in the edgeconfig
+type NetworkGroup struct {
+	Name        string
+	Description string         `edge:"description,omitempty"`
+	Networks    []netip.Prefix `edge:"network"`
+}
+
+// IPv6NetworkGroup is a single ipv6 network group in the edgeconfig
+type IPv6NetworkGroup struct {
+	Name        string
+	Description string         `edge:"description,omitempty"`
+	Networks    []netip.Prefix `edge:"ipv6-network"`
+}
+
+// PortGroup is a single port group in the edgeconfig
+type PortGroup struct {
+	Name        string
+	Description string       `edge:"description,omitempty"`
+	Ports       []types.Port `edge:"port"`
 }
 
 // FirewallZone is a specific zone for the firewall
@@ -103,18 +181,70 @@ type FirewallZone struct {
 	Name          string
 	DefaultAction string         `edge:"default-action"`
 	Description   string         `edge:"description"`
-	Rules         []FirewallRule `edge:"rule {{ .Count }}"`
+	Rules         []FirewallRule `edge:"rule {{ .Number }}"`
 }
 
 // FirewallRule is a single rule within a firewall zone
 type FirewallRule struct {
-	Action      string              `edge:"action"`
-	Description string              `edge:"description,omitempty"`
-	Destination types.AddressPort   `edge:"destination,omitempty"`
-	Log         types.EnableDisable `edge:"log"`
-	Protocol    types.Protocol      `edge:"protocol,omitempty"`
-	Source      types.AddressPort   `edge:"source,omitempty"`
-	State       FirewallRuleState   `edge:"state,omitempty"`
+	Number      uint32
+	Action      string               `edge:"action"`
+	Description string               `edge:"description,omitempty"`
+	Destination types.AddressPort    `edge:"destination,omitempty"`
+	Disable     types.KeyWhenEnabled `edge:"disable,omitempty"`
+	Fragment    *FirewallRuleMatch   `edge:"fragment"`
+	ICMP        *FirewallICMP        `edge:"icmp"`
+	ICMPv6      *FirewallICMPv6      `edge:"icmpv6"`
+	IPsec       *FirewallRuleMatch   `edge:"ipsec"`
+	Limit       *FirewallLimit       `edge:"limit"`
+	Log         types.EnableDisable  `edge:"log"`
+	Protocol    types.Protocol       `edge:"protocol,omitempty"`
+	Recent      *FirewallRecent      `edge:"recent"`
+	Source      types.AddressPort    `edge:"source,omitempty"`
+	State       FirewallRuleState    `edge:"state,omitempty"`
+	TCP         *FirewallTCP         `edge:"tcp"`
+	Time        *FirewallTime        `edge:"time"`
+}
+
+// FirewallRuleMatch is a block holding a single match keyword, like "fragment { match-frag }"
+type FirewallRuleMatch struct {
+	Match string `edge:"."`
+}
+
+// FirewallICMP matches icmp messages
+type FirewallICMP struct {
+	Code     *uint8 `edge:"code"`
+	Type     *uint8 `edge:"type"`
+	TypeName string `edge:"type-name,omitempty"`
+}
+
+// FirewallICMPv6 matches icmpv6 messages, where type is a name, type number, or type/code
+type FirewallICMPv6 struct {
+	Type string `edge:"type"`
+}
+
+// FirewallLimit rate limits matching packets
+type FirewallLimit struct {
+	Burst uint32 `edge:"burst,omitempty"`
+	Rate  string `edge:"rate"`
+}
+
+// FirewallRecent matches recently seen sources
+type FirewallRecent struct {
+	Count uint32 `edge:"count"`
+	Time  uint32 `edge:"time"`
+}
+
+// FirewallTCP matches tcp flags, such as SYN,!ACK
+type FirewallTCP struct {
+	Flags string `edge:"flags"`
+}
+
+// FirewallTime matches packets by time of day and day of week
+type FirewallTime struct {
+	StartTime string               `edge:"starttime,omitempty"`
+	StopTime  string               `edge:"stoptime,omitempty"`
+	UTC       types.KeyWhenEnabled `edge:"utc,omitempty"`
+	Weekdays  string               `edge:"weekdays,omitempty"`
 }
 
 // FirewallRuleState connection state settings
@@ -190,6 +320,7 @@ type InterfaceFirewallAssignment struct {
 
 // InterfaceFirewallZone The name of the zone for the firewall
 type InterfaceFirewallZone struct {
+	Modify string `edge:"modify,omitempty"`
 	Name   string `edge:"name,omitempty"`
 	V6Name string `edge:"ipv6-name,omitempty"`
 }
@@ -290,6 +421,13 @@ type BGPRedistribute struct {
 // StaticProtocol Wraps all static routes
 type StaticProtocol struct {
 	Routes []StaticRoute `edge:"route{{ .RouteSuffix }} {{ .Route }}"`
+	Tables []StaticTable `edge:"table {{ .Table }}"`
+}
+
+// StaticTable is a routing table for policy routes, with its own static routes
+type StaticTable struct {
+	Table  uint8
+	Routes []StaticRoute `edge:"route{{ .RouteSuffix }} {{ .Route }}"`
 }
 
 // StaticRoute is a static route in edgeconfig format
@@ -309,12 +447,12 @@ type NextHop struct {
 
 // RouterServices Available services on the router
 type RouterServices struct {
-	DHCPServer DHCPServer  `edge:"dhcp-server"`
-	DNS        DNSService  `edge:"dns,omitempty"`
-	GUI        GUIService  `edge:"gui,omitempty"`
-	NAT        NatService  `edge:"nat,omitempty"`
-	SSH        SSHService  `edge:"ssh,omitempty"`
-	UNMS       UNMSService `edge:"unms"`
+	DHCPServer DHCPServer   `edge:"dhcp-server"`
+	DNS        DNSService   `edge:"dns,omitempty"`
+	GUI        GUIService   `edge:"gui,omitempty"`
+	NAT        NatService   `edge:"nat,omitempty"`
+	SSH        SSHService   `edge:"ssh,omitempty"`
+	UNMS       *UNMSService `edge:"unms"`
 }
 
 // DHCPServer information about enabled DHCP servers
@@ -383,7 +521,7 @@ type GUIService struct {
 
 // NatService NAT settings
 type NatService struct {
-	// Must be in distinct numbering blocks, so splitting to make that easier
+	// Must be in distinct numbering blocks (destination below 5000, source from 5000), so splitting to make that easier
 	Dest []NatRule
 	Src  []NatRule
 }
@@ -393,34 +531,23 @@ func (ns NatService) MarshalEdge() ([]byte, error) {
 	return nil, fmt.Errorf("marshaledge not implemented for NatService")
 }
 
-// MarshalEdgeWithDepth custom marshaller for NatService to ensure we get the numbers correct
+// MarshalEdgeWithDepth custom marshaller for NatService to write destination rules before source rules
 func (ns NatService) MarshalEdgeWithDepth(depth int) ([]byte, error) {
 	var buffer bytes.Buffer
-	destCount := 1
-	srcCount := 5000
-	for _, rule := range ns.Dest {
-		buffer.WriteString(fmt.Sprintf("%srule %d {\n", strings.Repeat(" ", depth), destCount))
-		err := marshalValue(&buffer, reflect.ValueOf(rule), depth+4)
-		if err != nil {
-			return nil, err
-		}
-		buffer.WriteString(fmt.Sprintf("%s}\n", strings.Repeat(" ", depth)))
-		destCount++
-	}
-	for _, rule := range ns.Src {
-		buffer.WriteString(fmt.Sprintf("%srule %d {\n", strings.Repeat(" ", depth), srcCount))
+	for _, rule := range append(append([]NatRule{}, ns.Dest...), ns.Src...) {
+		buffer.WriteString(fmt.Sprintf("%srule %d {\n", strings.Repeat(" ", depth), rule.Number))
 		err := marshalValue(&buffer, reflect.ValueOf(rule), depth+4)
 		if err != nil {
 			return nil, err
 		}
 		buffer.WriteString(fmt.Sprintf("%s}\n", strings.Repeat(" ", depth)))
-		srcCount++
 	}
 	return buffer.Bytes(), nil
 }
 
 // NatRule a single NAT rule
 type NatRule struct {
+	Number            uint32
 	Name              string              `edge:"description"`
 	Destination       types.AddressPort   `edge:"destination,omitempty"`
 	InboundInterface  string              `edge:"inbound-interface,omitempty"`
@@ -444,13 +571,82 @@ type UNMSService struct{}
 
 // RouterSystem is the system config for the router
 type RouterSystem struct {
-	AnalyticsHandler AnalyticsHandler `edge:"analytics-handler"`
-	CrashHandler     CrashHandler     `edge:"crash-handler"`
-	HostName         string           `edge:"host-name"`
-	Login            RouterLogin      `edge:"login,omitempty"`
-	NTP              NTPServers       `edge:"ntp,omitempty"`
-	Syslog           Syslog           `edge:"syslog,omitempty"`
-	TimeZone         string           `edge:"time-zone,omitempty"`
+	AnalyticsHandler *AnalyticsHandler `edge:"analytics-handler"`
+	Conntrack        *Conntrack        `edge:"conntrack"`
+	CrashHandler     *CrashHandler     `edge:"crash-handler"`
+	HostName         string            `edge:"host-name"`
+	Login            RouterLogin       `edge:"login,omitempty"`
+	NTP              NTPServers        `edge:"ntp,omitempty"`
+	Offload          *Offload          `edge:"offload"`
+	Syslog           Syslog            `edge:"syslog,omitempty"`
+	TimeZone         string            `edge:"time-zone,omitempty"`
+}
+
+// Conntrack connection tracking settings
+type Conntrack struct {
+	ExpectTableSize uint32           `edge:"expect-table-size,omitempty"`
+	HashSize        uint32           `edge:"hash-size,omitempty"`
+	Modules         ConntrackModules `edge:"modules,omitempty"`
+	TableSize       uint32           `edge:"table-size,omitempty"`
+	Timeout         ConntrackTimeout `edge:"timeout,omitempty"`
+}
+
+// ConntrackModules connection tracking helpers, which are enabled unless disabled here
+type ConntrackModules struct {
+	FTP  *ConntrackModule `edge:"ftp"`
+	GRE  *ConntrackModule `edge:"gre"`
+	H323 *ConntrackModule `edge:"h323"`
+	PPTP *ConntrackModule `edge:"pptp"`
+	SIP  *ConntrackModule `edge:"sip"`
+	TFTP *ConntrackModule `edge:"tftp"`
+}
+
+// ConntrackModule a single connection tracking helper
+type ConntrackModule struct {
+	Disable types.KeyWhenEnabled `edge:"disable,omitempty"`
+}
+
+// ConntrackTimeout connection tracking timeouts, in seconds
+type ConntrackTimeout struct {
+	ICMP  uint32              `edge:"icmp,omitempty"`
+	Other uint32              `edge:"other,omitempty"`
+	TCP   ConntrackTCPTimeout `edge:"tcp,omitempty"`
+	UDP   ConntrackUDPTimeout `edge:"udp,omitempty"`
+}
+
+// ConntrackTCPTimeout tcp timeouts by connection state
+type ConntrackTCPTimeout struct {
+	Close       uint32 `edge:"close,omitempty"`
+	CloseWait   uint32 `edge:"close-wait,omitempty"`
+	Established uint32 `edge:"established,omitempty"`
+	FinWait     uint32 `edge:"fin-wait,omitempty"`
+	LastAck     uint32 `edge:"last-ack,omitempty"`
+	SynRecv     uint32 `edge:"syn-recv,omitempty"`
+	SynSent     uint32 `edge:"syn-sent,omitempty"`
+	TimeWait    uint32 `edge:"time-wait,omitempty"`
+}
+
+// ConntrackUDPTimeout udp timeouts
+type ConntrackUDPTimeout struct {
+	Other  uint32 `edge:"other,omitempty"`
+	Stream uint32 `edge:"stream,omitempty"`
+}
+
+// Offload hardware offload settings
+type Offload struct {
+	HWNAT *types.EnableDisable `edge:"hwnat"`
+	IPSec *types.EnableDisable `edge:"ipsec"`
+	IPv4  *OffloadFamily       `edge:"ipv4"`
+	IPv6  *OffloadFamily       `edge:"ipv6"`
+}
+
+// OffloadFamily forwarding offload settings for ipv4 or ipv6
+type OffloadFamily struct {
+	Bonding    *types.EnableDisable `edge:"bonding"`
+	Forwarding *types.EnableDisable `edge:"forwarding"`
+	GRE        *types.EnableDisable `edge:"gre"`
+	PPPoE      *types.EnableDisable `edge:"pppoe"`
+	VLAN       *types.EnableDisable `edge:"vlan"`
 }
 
 // AnalyticsHandler settings for analytics
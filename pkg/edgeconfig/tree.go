@@ -0,0 +1,378 @@
+package edgeconfig
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Node is a single node in a generic config.boot tree
+// Name is the full text of the line without braces, such as "ethernet eth0" for a block
+// or "address 192.0.2.1/24" for a leaf, so that paths map directly to set commands
+type Node struct {
+	Name     string
+	Block    bool
+	Children []*Node
+}
+
+// ParseTree parses config.boot formatted config into a tree, skipping comments (including the version footer)
+// The returned root node is an unnamed block containing the top level sections
+func ParseTree(config []byte) (*Node, error) {
+	root := &Node{Block: true}
+	stack := []*Node{root}
+	inComment := false
+
+	for lineNum, line := range strings.Split(string(config), "\n") {
+		line = strings.TrimSpace(line)
+
+		if inComment {
+			if strings.Contains(line, "*/") {
+				inComment = false
+			}
+			continue
+		}
+		if strings.HasPrefix(line, "/*") {
+			inComment = !strings.Contains(line, "*/")
+			continue
+		}
+		if line == "" {
+			continue
+		}
+
+		parent := stack[len(stack)-1]
+		switch {
+		case line == "}":
+			if len(stack) == 1 {
+				return nil, fmt.Errorf("unexpected } on line %d", lineNum+1)
+			}
+			stack = stack[:len(stack)-1]
+		case strings.HasSuffix(line, "{}"):
+			parent.Children = append(parent.Children, &Node{Name: strings.TrimSpace(strings.TrimSuffix(line, "{}")), Block: true})
+		case strings.HasSuffix(line, "{"):
+			node := &Node{Name: strings.TrimSpace(strings.TrimSuffix(line, "{")), Block: true}
+			parent.Children = append(parent.Children, node)
+			stack = append(stack, node)
+		default:
+			parent.Children = append(parent.Children, &Node{Name: line})
+		}
+	}
+
+	if len(stack) != 1 {
+		return nil, fmt.Errorf("unclosed block %s", stack[len(stack)-1].Name)
+	}
+
+	return root, nil
+}
+
+// Child returns the direct child with the given name, or nil
+func (n *Node) Child(name string) *Node {
+	for _, child := range n.Children {
+		if child.Name == name {
+			return child
+		}
+	}
+	return nil
+}
+
+// Marshal returns the tree in config.boot format
+func (n *Node) Marshal() []byte {
+	var buffer bytes.Buffer
+	for _, child := range n.Children {
+		child.marshal(&buffer, 0)
+	}
+	return buffer.Bytes()
+}
+
+func (n *Node) marshal(buffer *bytes.Buffer, depth int) {
+	indent := strings.Repeat(" ", depth)
+	if !n.Block {
+		buffer.WriteString(fmt.Sprintf("%s%s\n", indent, n.Name))
+		return
+	}
+
+	buffer.WriteString(fmt.Sprintf("%s%s {\n", indent, n.Name))
+	for _, child := range n.Children {
+		child.marshal(buffer, depth+4)
+	}
+	buffer.WriteString(fmt.Sprintf("%s}\n", indent))
+}
+
+// Map returns the tree as nested maps, in the same shape EdgeOS uses for its own JSON config
+// Tag nodes such as "ethernet eth0" nest the value under the keyword, leaf values are strings,
+// repeated leaves become lists, and leaves without a value (such as "disable") are nil
+func (n *Node) Map() map[string]interface{} {
+	values := map[string]interface{}{}
+	for _, child := range n.Children {
+		child.addTo(values)
+	}
+	return values
+}
+
+func (n *Node) addTo(values map[string]interface{}) {
+	key, value, _ := strings.Cut(n.Name, " ")
+	value = strings.Trim(value, `"`)
+
+	if n.Block {
+		if value != "" {
+			values = childMap(values, key)
+			key = value
+		}
+		children := childMap(values, key)
+		for _, child := range n.Children {
+			child.addTo(children)
+		}
+		return
+	}
+
+	var leaf interface{}
+	if value != "" {
+		leaf = value
+	}
+	switch existing := values[key].(type) {
+	case nil:
+		if _, ok := values[key]; ok {
+			values[key] = []interface{}{nil, leaf}
+		} else {
+			values[key] = leaf
+		}
+	case []interface{}:
+		values[key] = append(existing, leaf)
+	default:
+		values[key] = []interface{}{existing, leaf}
+	}
+}
+
+// childMap returns the map stored under key, adding an empty one if there isn't one yet
+func childMap(values map[string]interface{}, key string) map[string]interface{} {
+	child, ok := values[key].(map[string]interface{})
+	if !ok {
+		child = map[string]interface{}{}
+		values[key] = child
+	}
+	return child
+}
+
+// SetCommands returns the EdgeOS `set` commands that create everything in the tree
+func (n *Node) SetCommands() []string {
+	var commands []string
+	for _, child := range n.Children {
+		commands = append(commands, child.setCommands("")...)
+	}
+	return commands
+}
+
+func (n *Node) setCommands(prefix string) []string {
+	path := strings.TrimSpace(prefix + " " + n.Name)
+	if !n.Block || len(n.Children) == 0 {
+		return []string{"set " + path}
+	}
+
+	var commands []string
+	for _, child := range n.Children {
+		commands = append(commands, child.setCommands(path)...)
+	}
+	return commands
+}
+
+// Diff returns the `delete` and `set` commands that turn the live tree into the desired tree
+// Deletes are listed first, followed by sets, so the commands can be run in order inside a single commit
+func Diff(live, desired *Node) []string {
+	deletes, sets := diffNodes("", live, desired)
+	return append(deletes, sets...)
+}
+
+func diffNodes(path string, live, desired *Node) ([]string, []string) {
+	var deletes, sets []string
+
+	// Single valued leaves (like mtu) that exist on both sides only need a set to replace the old value
+	liveKeys := leafKeyCounts(live)
+	desiredKeys := leafKeyCounts(desired)
+	replaced := func(node *Node) bool {
+		key := leafKey(node)
+		return !node.Block && liveKeys[key] == 1 && desiredKeys[key] == 1
+	}
+
+	for _, liveChild := range live.Children {
+		desiredChild := desired.Child(liveChild.Name)
+		if desiredChild != nil && desiredChild.Block == liveChild.Block {
+			continue
+		}
+		if replaced(liveChild) {
+			continue
+		}
+		deletes = append(deletes, "delete "+strings.TrimSpace(path+" "+liveChild.Name))
+	}
+
+	for _, desiredChild := range desired.Children {
+		liveChild := live.Child(desiredChild.Name)
+		if liveChild == nil || liveChild.Block != desiredChild.Block {
+			sets = append(sets, desiredChild.setCommands(path)...)
+			continue
+		}
+		if desiredChild.Block {
+			childDeletes, childSets := diffNodes(strings.TrimSpace(path+" "+desiredChild.Name), liveChild, desiredChild)
+			deletes = append(deletes, childDeletes...)
+			sets = append(sets, childSets...)
+		}
+	}
+
+	return deletes, sets
+}
+
+// Renumbered finds rules that keep the same content but move to a different rule number between the live and desired
+// trees, such as when a rule is inserted above rules that are numbered automatically
+// Each entry reads "<path to the live rule> is now rule <number>"
+func Renumbered(live, desired *Node) []string {
+	return renumbered("", live, desired)
+}
+
+func renumbered(path string, live, desired *Node) []string {
+	var moves []string
+	claimed := map[*Node]bool{}
+
+	for _, liveChild := range live.Children {
+		if !liveChild.Block {
+			continue
+		}
+		childPath := strings.TrimSpace(path + " " + liveChild.Name)
+		desiredChild := desired.Child(liveChild.Name)
+
+		if !isRule(liveChild) {
+			if desiredChild != nil && desiredChild.Block {
+				moves = append(moves, renumbered(childPath, liveChild, desiredChild)...)
+			}
+			continue
+		}
+
+		body := ruleBody(liveChild)
+		if body == "" || (desiredChild != nil && ruleBody(desiredChild) == body) {
+			continue
+		}
+		for _, candidate := range desired.Children {
+			if claimed[candidate] || !candidate.Block || !isRule(candidate) || ruleBody(candidate) != body {
+				continue
+			}
+			// A rule that already has this content at the new number isn't moving
+			if existing := live.Child(candidate.Name); existing != nil && ruleBody(existing) == body {
+				continue
+			}
+			claimed[candidate] = true
+			moves = append(moves, childPath+" is now "+candidate.Name)
+			break
+		}
+	}
+
+	return moves
+}
+
+// isRule checks for a numbered rule block, such as "rule 10" in a firewall ruleset or the NAT service
+func isRule(node *Node) bool {
+	return node.Block && leafKey(node) == "rule"
+}
+
+// ruleBody is the rule's content without its number, so the same rule can be recognized at a different number
+func ruleBody(node *Node) string {
+	commands := node.SetCommands()
+	slices.Sort(commands)
+	return strings.Join(commands, "\n")
+}
+
+// leafKey is the first word of a leaf, such as "mtu" for "mtu 9000"
+func leafKey(node *Node) string {
+	key, _, _ := strings.Cut(node.Name, " ")
+	return key
+}
+
+func leafKeyCounts(node *Node) map[string]int {
+	counts := map[string]int{}
+	for _, child := range node.Children {
+		if !child.Block {
+			counts[leafKey(child)]++
+		}
+	}
+	return counts
+}
+
+// treeMatch is a node found by a path lookup, along with the names of the blocks leading to it
+type treeMatch struct {
+	parents []string
+	node    *Node
+}
+
+// lookup finds every node matching the space separated path, such as "service unms" or "interfaces ethernet eth0"
+// A path can stop partway through a node's name, so "system name-server" matches every name-server leaf
+func (n *Node) lookup(path []string, parents []string) []treeMatch {
+	var matches []treeMatch
+	for _, child := range n.Children {
+		childTokens := splitWords(child.Name)
+		switch {
+		case len(path) <= len(childTokens) && tokensEqual(childTokens[:len(path)], path):
+			matches = append(matches, treeMatch{parents: parents, node: child})
+		case child.Block && len(path) > len(childTokens) && tokensEqual(path[:len(childTokens)], childTokens):
+			childParents := append(append([]string{}, parents...), child.Name)
+			matches = append(matches, child.lookup(path[len(childTokens):], childParents)...)
+		}
+	}
+	return matches
+}
+
+// remove deletes every node matching the path
+func (n *Node) remove(path []string) {
+	var kept []*Node
+	for _, child := range n.Children {
+		childTokens := splitWords(child.Name)
+		switch {
+		case len(path) <= len(childTokens) && tokensEqual(childTokens[:len(path)], path):
+			continue
+		case child.Block && len(path) > len(childTokens) && tokensEqual(path[:len(childTokens)], childTokens):
+			child.remove(path[len(childTokens):])
+		}
+		kept = append(kept, child)
+	}
+	n.Children = kept
+}
+
+// Graft replaces everything at the path with whatever exists at the same path in source
+// If source has nothing at the path, it is removed. Any blocks leading to the grafted nodes are created as needed
+func (n *Node) Graft(path string, source *Node) {
+	tokens := splitWords(path)
+	if len(tokens) == 0 {
+		return
+	}
+
+	n.remove(tokens)
+	for _, match := range source.lookup(tokens, nil) {
+		parent := n
+		for _, name := range match.parents {
+			next := parent.Child(name)
+			if next == nil {
+				next = &Node{Name: name, Block: true}
+				parent.Children = append(parent.Children, next)
+			}
+			parent = next
+		}
+		parent.Children = append(parent.Children, match.node.Clone())
+	}
+}
+
+// Clone returns a deep copy of the node
+func (n *Node) Clone() *Node {
+	clone := &Node{Name: n.Name, Block: n.Block}
+	for _, child := range n.Children {
+		clone.Children = append(clone.Children, child.Clone())
+	}
+	return clone
+}
+
+func tokensEqual(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
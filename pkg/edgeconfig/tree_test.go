@@ -0,0 +1,317 @@
+package edgeconfig
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+)
+
+func TestNodeMap(t *testing.T) {
+	tree, err := ParseTree([]byte(`firewall {
+    name WAN_IN {
+        default-action drop
+        description "WAN to internal"
+        rule 10 {
+            action accept
+            disable
+        }
+    }
+}
+interfaces {
+    ethernet eth0 {
+        address 192.0.2.1/24
+        address 2001:db8::1/64
+    }
+    ethernet eth1 {
+        address dhcp
+    }
+}
+service {
+    gui {
+    }
+}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := json.Marshal(tree.Map())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `{"firewall":{"name":{"WAN_IN":{"default-action":"drop","description":"WAN to internal","rule":{"10":{"action":"accept","disable":null}}}}},` +
+		`"interfaces":{"ethernet":{"eth0":{"address":["192.0.2.1/24","2001:db8::1/64"]},"eth1":{"address":"dhcp"}}},` +
+		`"service":{"gui":{}}}`
+	if string(got) != want {
+		t.Errorf("Map() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestSetCommands(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		want   []string
+	}{
+		{
+			name:   "leaves",
+			config: "system {\n    host-name router01\n    time-zone UTC\n}\n",
+			want:   []string{"set system host-name router01", "set system time-zone UTC"},
+		},
+		{
+			name:   "multi value leaves",
+			config: "system {\n    name-server 1.1.1.1\n    name-server 8.8.8.8\n}\n",
+			want:   []string{"set system name-server 1.1.1.1", "set system name-server 8.8.8.8"},
+		},
+		{
+			name:   "quoted values",
+			config: "interfaces {\n    ethernet eth0 {\n        description \"WAN link\"\n    }\n}\n",
+			want:   []string{`set interfaces ethernet eth0 description "WAN link"`},
+		},
+		{
+			name: "nested tag nodes",
+			config: `firewall {
+    name WAN_IN {
+        default-action drop
+        rule 10 {
+            action accept
+            state {
+                established enable
+            }
+        }
+    }
+}
+`,
+			want: []string{
+				"set firewall name WAN_IN default-action drop",
+				"set firewall name WAN_IN rule 10 action accept",
+				"set firewall name WAN_IN rule 10 state established enable",
+			},
+		},
+		{
+			name:   "empty blocks",
+			config: "service {\n    gui {\n    }\n    ssh {}\n}\n",
+			want:   []string{"set service gui", "set service ssh"},
+		},
+		{
+			name:   "valueless leaves",
+			config: "interfaces {\n    ethernet eth4 {\n        disable\n    }\n}\n",
+			want:   []string{"set interfaces ethernet eth4 disable"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tree, err := ParseTree([]byte(tt.config))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := tree.SetCommands(); !slices.Equal(got, tt.want) {
+				t.Errorf("SetCommands() =\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name    string
+		live    string
+		desired string
+		want    []string
+	}{
+		{
+			name:    "identical",
+			live:    "system {\n    host-name router01\n}\n",
+			desired: "system {\n    host-name router01\n}\n",
+			want:    nil,
+		},
+		{
+			name:    "added leaf",
+			live:    "system {\n    host-name router01\n}\n",
+			desired: "system {\n    host-name router01\n    time-zone UTC\n}\n",
+			want:    []string{"set system time-zone UTC"},
+		},
+		{
+			name:    "removed leaf",
+			live:    "system {\n    host-name router01\n    time-zone UTC\n}\n",
+			desired: "system {\n    host-name router01\n}\n",
+			want:    []string{"delete system time-zone UTC"},
+		},
+		{
+			name:    "changed single value leaf is only set",
+			live:    "interfaces {\n    ethernet eth0 {\n        mtu 1500\n    }\n}\n",
+			desired: "interfaces {\n    ethernet eth0 {\n        mtu 9000\n    }\n}\n",
+			want:    []string{"set interfaces ethernet eth0 mtu 9000"},
+		},
+		{
+			name:    "multi value leaves",
+			live:    "system {\n    name-server 1.1.1.1\n    name-server 8.8.8.8\n}\n",
+			desired: "system {\n    name-server 1.1.1.1\n    name-server 9.9.9.9\n}\n",
+			want:    []string{"delete system name-server 8.8.8.8", "set system name-server 9.9.9.9"},
+		},
+		{
+			name:    "single value becomes multi value",
+			live:    "system {\n    name-server 1.1.1.1\n}\n",
+			desired: "system {\n    name-server 8.8.8.8\n    name-server 9.9.9.9\n}\n",
+			want:    []string{"delete system name-server 1.1.1.1", "set system name-server 8.8.8.8", "set system name-server 9.9.9.9"},
+		},
+		{
+			name:    "quoted values",
+			live:    "interfaces {\n    ethernet eth0 {\n        description \"old link\"\n    }\n}\n",
+			desired: "interfaces {\n    ethernet eth0 {\n        description \"WAN link\"\n    }\n}\n",
+			want:    []string{`set interfaces ethernet eth0 description "WAN link"`},
+		},
+		{
+			name: "nested tag nodes",
+			live: `firewall {
+    name WAN_IN {
+        default-action drop
+        rule 10 {
+            action drop
+            protocol tcp
+        }
+        rule 20 {
+            action accept
+        }
+    }
+}
+`,
+			desired: `firewall {
+    name WAN_IN {
+        default-action drop
+        rule 10 {
+            action accept
+        }
+        rule 30 {
+            action accept
+            protocol udp
+        }
+    }
+}
+`,
+			want: []string{
+				"delete firewall name WAN_IN rule 20",
+				"delete firewall name WAN_IN rule 10 protocol tcp",
+				"set firewall name WAN_IN rule 10 action accept",
+				"set firewall name WAN_IN rule 30 action accept",
+				"set firewall name WAN_IN rule 30 protocol udp",
+			},
+		},
+		{
+			name:    "deleting a whole block",
+			live:    "service {\n    gui {\n        https-port 443\n    }\n    upnp2 {\n        listen-on eth1\n        wan eth0\n    }\n}\n",
+			desired: "service {\n    gui {\n        https-port 443\n    }\n}\n",
+			want:    []string{"delete service upnp2"},
+		},
+		{
+			name:    "deleting a top level section",
+			live:    "protocols {\n    bgp 65535 {\n        parameters {\n            router-id 192.0.2.1\n        }\n    }\n}\nsystem {\n    host-name router01\n}\n",
+			desired: "system {\n    host-name router01\n}\n",
+			want:    []string{"delete protocols"},
+		},
+		{
+			name:    "leaf replaced by a block",
+			live:    "service {\n    dns disable\n}\n",
+			desired: "service {\n    dns {\n        forwarding {\n            cache-size 150\n        }\n    }\n}\n",
+			want:    []string{"delete service dns disable", "set service dns forwarding cache-size 150"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			live, err := ParseTree([]byte(tt.live))
+			if err != nil {
+				t.Fatal(err)
+			}
+			desired, err := ParseTree([]byte(tt.desired))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := Diff(live, desired); !slices.Equal(got, tt.want) {
+				t.Errorf("Diff() =\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenumbered(t *testing.T) {
+	ruleset := func(rules ...string) string {
+		config := "firewall {\n    name WAN_IN {\n        default-action drop\n"
+		for _, rule := range rules {
+			config += "        " + rule + "\n"
+		}
+		return config + "    }\n}\n"
+	}
+
+	tests := []struct {
+		name    string
+		live    string
+		desired string
+		want    []string
+	}{
+		{
+			name:    "unchanged rules",
+			live:    ruleset("rule 10 {\n action accept\n protocol tcp\n}", "rule 20 {\n action drop\n}"),
+			desired: ruleset("rule 10 {\n action accept\n protocol tcp\n}", "rule 20 {\n action drop\n}"),
+		},
+		{
+			name:    "changed rule keeps its number",
+			live:    ruleset("rule 10 {\n action accept\n}"),
+			desired: ruleset("rule 10 {\n action drop\n}"),
+		},
+		{
+			name:    "rule inserted above numbered rules",
+			live:    ruleset("rule 10 {\n action accept\n protocol tcp\n}", "rule 20 {\n action drop\n}"),
+			desired: ruleset("rule 10 {\n action accept\n protocol icmp\n}", "rule 20 {\n action accept\n protocol tcp\n}", "rule 30 {\n action drop\n}"),
+			want: []string{
+				"firewall name WAN_IN rule 10 is now rule 20",
+				"firewall name WAN_IN rule 20 is now rule 30",
+			},
+		},
+		{
+			name:    "rule removed from the top",
+			live:    ruleset("rule 10 {\n action accept\n protocol icmp\n}", "rule 20 {\n action drop\n}"),
+			desired: ruleset("rule 10 {\n action drop\n}"),
+			want:    []string{"firewall name WAN_IN rule 20 is now rule 10"},
+		},
+		{
+			name:    "leaf order doesn't matter",
+			live:    ruleset("rule 10 {\n protocol tcp\n action accept\n}"),
+			desired: ruleset("rule 20 {\n action accept\n protocol tcp\n}"),
+			want:    []string{"firewall name WAN_IN rule 10 is now rule 20"},
+		},
+		{
+			name:    "identical rules are each matched once",
+			live:    ruleset("rule 10 {\n action drop\n}", "rule 20 {\n action drop\n}"),
+			desired: ruleset("rule 30 {\n action drop\n}"),
+			want:    []string{"firewall name WAN_IN rule 10 is now rule 30"},
+		},
+		{
+			name:    "nat rules",
+			live:    "service {\n    nat {\n        rule 5000 {\n            type masquerade\n        }\n    }\n}\n",
+			desired: "service {\n    nat {\n        rule 5010 {\n            type masquerade\n        }\n    }\n}\n",
+			want:    []string{"service nat rule 5000 is now rule 5010"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			live, err := ParseTree([]byte(tt.live))
+			if err != nil {
+				t.Fatal(err)
+			}
+			desired, err := ParseTree([]byte(tt.desired))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := Renumbered(live, desired); !slices.Equal(got, tt.want) {
+				t.Errorf("Renumbered() =\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,277 @@
+package lint
+
+import (
+	"net/netip"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/cmmarslender/edgefig/pkg/config"
+	"github.com/cmmarslender/edgefig/pkg/types"
+)
+
+// covers returns true if every packet matched by rule b is also matched by rule a, so b can never match after a
+// Rules that only match some of the time (limit and recent) never cover another rule
+func covers(groups config.FirewallGroups, a, b config.FirewallRule) bool {
+	if !coversProtocol(a.Protocol, b.Protocol) || !coversState(a, b) {
+		return false
+	}
+	if a.Limit != nil || a.Recent != nil {
+		return false
+	}
+	if (a.ICMP != nil && !reflect.DeepEqual(a.ICMP, b.ICMP)) ||
+		(a.TCPFlags != "" && a.TCPFlags != b.TCPFlags) ||
+		(a.Time != nil && !reflect.DeepEqual(a.Time, b.Time)) ||
+		(a.Fragment != nil && !reflect.DeepEqual(a.Fragment, b.Fragment)) ||
+		(a.IPsec != nil && !reflect.DeepEqual(a.IPsec, b.IPsec)) {
+		return false
+	}
+
+	return coversAddressPort(groups, a.Source, b.Source) && coversAddressPort(groups, a.Destination, b.Destination)
+}
+
+// unconditional returns true if the rule matches every packet
+func unconditional(rule config.FirewallRule) bool {
+	return (rule.Protocol == "" || rule.Protocol == types.ProtocolAll) &&
+		!hasState(rule) &&
+		rule.ICMP == nil && rule.TCPFlags == "" && rule.Limit == nil && rule.Recent == nil && rule.Time == nil &&
+		rule.Fragment == nil && rule.IPsec == nil &&
+		reflect.DeepEqual(rule.Source, types.AddressPort{}) && reflect.DeepEqual(rule.Destination, types.AddressPort{})
+}
+
+func coversProtocol(a, b types.Protocol) bool {
+	switch a {
+	case "", types.ProtocolAll:
+		return true
+	case types.ProtocolTCPUDP:
+		return b == types.ProtocolTCP || b == types.ProtocolUDP || b == types.ProtocolTCPUDP
+	default:
+		return a == b
+	}
+}
+
+func hasState(rule config.FirewallRule) bool {
+	return bool(rule.Established || rule.Invalid || rule.New || rule.Related)
+}
+
+// coversState returns true if a matches every state b does, where a rule without states matches all of them
+func coversState(a, b config.FirewallRule) bool {
+	if !hasState(a) {
+		return true
+	}
+	if !hasState(b) {
+		return false
+	}
+	return bool((!b.Established || a.Established) && (!b.Invalid || a.Invalid) && (!b.New || a.New) && (!b.Related || a.Related))
+}
+
+// coversAddressPort returns true if every criteria set on a matches everything matched by b
+// Criteria on the same source or destination must all match, so b is narrower than a criteria of a if any one of
+// b's criteria of the same kind is
+func coversAddressPort(groups config.FirewallGroups, a, b types.AddressPort) bool {
+	bAddresses := addressCriteria(groups, b)
+	for _, criteria := range addressCriteria(groups, a) {
+		if !slices.ContainsFunc(bAddresses, func(bCriteria addressSet) bool { return criteria.contains(bCriteria) }) {
+			return false
+		}
+	}
+
+	if a.MACAddress != "" && !strings.EqualFold(a.MACAddress, b.MACAddress) {
+		return false
+	}
+
+	bPorts := portCriteria(groups, b)
+	for _, criteria := range portCriteria(groups, a) {
+		if !slices.ContainsFunc(bPorts, func(bCriteria portSet) bool { return criteria.contains(bCriteria) }) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// addressRange is an inclusive range of addresses
+type addressRange struct {
+	start netip.Addr
+	end   netip.Addr
+}
+
+// addressSet is the addresses matched by a single criteria, known is false when it refers to a group that doesn't exist
+type addressSet struct {
+	ranges []addressRange
+	known  bool
+}
+
+// contains returns true if every address in other is also in the set
+func (s addressSet) contains(other addressSet) bool {
+	if !s.known || !other.known {
+		return false
+	}
+	for _, r := range other.ranges {
+		if !slices.ContainsFunc(mergeRanges(s.ranges), func(m addressRange) bool {
+			return m.start.BitLen() == r.start.BitLen() && m.start.Compare(r.start) <= 0 && m.end.Compare(r.end) >= 0
+		}) {
+			return false
+		}
+	}
+	return true
+}
+
+// mergeRanges sorts the ranges, joining ranges that overlap or are next to each other
+func mergeRanges(ranges []addressRange) []addressRange {
+	sorted := slices.Clone(ranges)
+	slices.SortFunc(sorted, func(a, b addressRange) int { return a.start.Compare(b.start) })
+
+	var merged []addressRange
+	for _, r := range sorted {
+		if len(merged) > 0 {
+			last := &merged[len(merged)-1]
+			next := last.end.Next()
+			if last.end.BitLen() == r.start.BitLen() && (r.start.Compare(last.end) <= 0 || (next.IsValid() && r.start == next)) {
+				if r.end.Compare(last.end) > 0 {
+					last.end = r.end
+				}
+				continue
+			}
+		}
+		merged = append(merged, r)
+	}
+	return merged
+}
+
+// prefixRange returns the first and last address in the prefix
+func prefixRange(prefix netip.Prefix) addressRange {
+	prefix = prefix.Masked()
+	last := prefix.Addr().AsSlice()
+	for bit := prefix.Bits(); bit < len(last)*8; bit++ {
+		last[bit/8] |= 1 << (7 - bit%8)
+	}
+	end, _ := netip.AddrFromSlice(last)
+	return addressRange{start: prefix.Addr(), end: end}
+}
+
+// addressCriteria returns the address criteria of a source or destination
+func addressCriteria(groups config.FirewallGroups, ap types.AddressPort) []addressSet {
+	var sets []addressSet
+	if ap.Address.IsValid() {
+		sets = append(sets, addressSet{ranges: []addressRange{{ap.Address, ap.Address}}, known: true})
+	}
+	if ap.Prefix.IsValid() {
+		sets = append(sets, addressSet{ranges: []addressRange{prefixRange(ap.Prefix)}, known: true})
+	}
+	if ap.Range.Start.IsValid() {
+		sets = append(sets, addressSet{ranges: []addressRange{{ap.Range.Start, ap.Range.End}}, known: true})
+	}
+	if ap.Group.AddressGroup != "" {
+		sets = append(sets, addressGroupSet(groups.AddressGroups, ap.Group.AddressGroup))
+	}
+	if ap.Group.IPv6AddressGroup != "" {
+		sets = append(sets, addressGroupSet(groups.IPv6AddressGroups, ap.Group.IPv6AddressGroup))
+	}
+	if ap.Group.NetworkGroup != "" {
+		sets = append(sets, networkGroupSet(groups.NetworkGroups, ap.Group.NetworkGroup))
+	}
+	if ap.Group.IPv6NetworkGroup != "" {
+		sets = append(sets, networkGroupSet(groups.IPv6NetworkGroups, ap.Group.IPv6NetworkGroup))
+	}
+	return sets
+}
+
+func addressGroupSet(groups []config.AddressGroup, name string) addressSet {
+	idx := slices.IndexFunc(groups, func(group config.AddressGroup) bool { return group.Name == name })
+	if idx == -1 {
+		return addressSet{}
+	}
+	set := addressSet{known: true}
+	for _, member := range groups[idx].Addresses {
+		end := member.End
+		if !end.IsValid() {
+			end = member.Start
+		}
+		set.ranges = append(set.ranges, addressRange{member.Start, end})
+	}
+	return set
+}
+
+func networkGroupSet(groups []config.NetworkGroup, name string) addressSet {
+	idx := slices.IndexFunc(groups, func(group config.NetworkGroup) bool { return group.Name == name })
+	if idx == -1 {
+		return addressSet{}
+	}
+	set := addressSet{known: true}
+	for _, network := range groups[idx].Networks {
+		set.ranges = append(set.ranges, prefixRange(network))
+	}
+	return set
+}
+
+// portRange is an inclusive range of port numbers
+type portRange struct {
+	start uint64
+	end   uint64
+}
+
+// portSet is the ports matched by a single criteria, where service names are only compared by name
+type portSet struct {
+	ranges []portRange
+	names  []string
+	known  bool
+}
+
+// contains returns true if every port in other is also in the set
+func (s portSet) contains(other portSet) bool {
+	if !s.known || !other.known {
+		return false
+	}
+	for _, name := range other.names {
+		if !slices.Contains(s.names, name) {
+			return false
+		}
+	}
+	for _, r := range other.ranges {
+		for port := r.start; port <= r.end; port++ {
+			if !slices.ContainsFunc(s.ranges, func(sr portRange) bool { return port >= sr.start && port <= sr.end }) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// portCriteria returns the port criteria of a source or destination
+func portCriteria(groups config.FirewallGroups, ap types.AddressPort) []portSet {
+	var sets []portSet
+	if len(ap.Port) > 0 {
+		sets = append(sets, newPortSet(ap.Port))
+	}
+	if ap.Group.PortGroup != "" {
+		idx := slices.IndexFunc(groups.PortGroups, func(group config.PortGroup) bool { return group.Name == ap.Group.PortGroup })
+		if idx == -1 {
+			sets = append(sets, portSet{})
+		} else {
+			sets = append(sets, newPortSet(groups.PortGroups[idx].Ports))
+		}
+	}
+	return sets
+}
+
+func newPortSet(ports []types.Port) portSet {
+	set := portSet{known: true}
+	for _, port := range ports {
+		if num, err := strconv.ParseUint(string(port), 10, 16); err == nil {
+			set.ranges = append(set.ranges, portRange{num, num})
+			continue
+		}
+		if start, end, isRange := strings.Cut(string(port), "-"); isRange {
+			startNum, startErr := strconv.ParseUint(start, 10, 16)
+			endNum, endErr := strconv.ParseUint(end, 10, 16)
+			if startErr == nil && endErr == nil {
+				set.ranges = append(set.ranges, portRange{startNum, endNum})
+				continue
+			}
+		}
+		set.names = append(set.names, string(port))
+	}
+	return set
+}
@@ -0,0 +1,260 @@
+package lint
+
+import (
+	"net/netip"
+	"slices"
+	"testing"
+
+	"github.com/cmmarslender/edgefig/pkg/config"
+	"github.com/cmmarslender/edgefig/pkg/types"
+)
+
+func TestCovers(t *testing.T) {
+	groups := config.FirewallGroups{
+		AddressGroups: []config.AddressGroup{
+			{Name: "servers", Addresses: []types.GroupAddress{
+				{Start: netip.MustParseAddr("10.0.0.10")},
+				{Start: netip.MustParseAddr("10.0.0.20"), End: netip.MustParseAddr("10.0.0.29")},
+			}},
+		},
+		NetworkGroups: []config.NetworkGroup{
+			{Name: "lan", Networks: []netip.Prefix{netip.MustParsePrefix("10.0.0.0/25"), netip.MustParsePrefix("10.0.0.128/25")}},
+		},
+		PortGroups: []config.PortGroup{
+			{Name: "web", Ports: []types.Port{"80", "443"}},
+			{Name: "high", Ports: []types.Port{"1024-65535"}},
+		},
+	}
+
+	tests := []struct {
+		name string
+		a    config.FirewallRule
+		b    config.FirewallRule
+		want bool
+	}{
+		{
+			name: "empty rule covers everything",
+			a:    config.FirewallRule{},
+			b:    config.FirewallRule{Protocol: types.ProtocolTCP, Destination: types.AddressPort{Port: types.Ports{"22"}}},
+			want: true,
+		},
+		{
+			name: "protocol all covers tcp",
+			a:    config.FirewallRule{Protocol: types.ProtocolAll},
+			b:    config.FirewallRule{Protocol: types.ProtocolTCP},
+			want: true,
+		},
+		{
+			name: "tcp_udp covers udp",
+			a:    config.FirewallRule{Protocol: types.ProtocolTCPUDP},
+			b:    config.FirewallRule{Protocol: types.ProtocolUDP},
+			want: true,
+		},
+		{
+			name: "tcp does not cover udp",
+			a:    config.FirewallRule{Protocol: types.ProtocolTCP},
+			b:    config.FirewallRule{Protocol: types.ProtocolUDP},
+			want: false,
+		},
+		{
+			name: "tcp does not cover any protocol",
+			a:    config.FirewallRule{Protocol: types.ProtocolTCP},
+			b:    config.FirewallRule{},
+			want: false,
+		},
+		{
+			name: "prefix covers address inside it",
+			a:    config.FirewallRule{Source: types.AddressPort{Prefix: netip.MustParsePrefix("10.0.0.0/24")}},
+			b:    config.FirewallRule{Source: types.AddressPort{Address: netip.MustParseAddr("10.0.0.5")}},
+			want: true,
+		},
+		{
+			name: "prefix does not cover address outside it",
+			a:    config.FirewallRule{Source: types.AddressPort{Prefix: netip.MustParsePrefix("10.0.0.0/24")}},
+			b:    config.FirewallRule{Source: types.AddressPort{Address: netip.MustParseAddr("10.0.1.5")}},
+			want: false,
+		},
+		{
+			name: "source criteria does not cover destination criteria",
+			a:    config.FirewallRule{Source: types.AddressPort{Prefix: netip.MustParsePrefix("10.0.0.0/24")}},
+			b:    config.FirewallRule{Destination: types.AddressPort{Prefix: netip.MustParsePrefix("10.0.0.0/24")}},
+			want: false,
+		},
+		{
+			name: "adjacent networks in a group cover the whole range",
+			a:    config.FirewallRule{Destination: types.AddressPort{Group: types.AddressGroup{NetworkGroup: "lan"}}},
+			b:    config.FirewallRule{Destination: types.AddressPort{Prefix: netip.MustParsePrefix("10.0.0.64/26")}},
+			want: true,
+		},
+		{
+			name: "network group covers address group within it",
+			a:    config.FirewallRule{Destination: types.AddressPort{Group: types.AddressGroup{NetworkGroup: "lan"}}},
+			b:    config.FirewallRule{Destination: types.AddressPort{Group: types.AddressGroup{AddressGroup: "servers"}}},
+			want: true,
+		},
+		{
+			name: "address group range does not cover a wider range",
+			a:    config.FirewallRule{Destination: types.AddressPort{Group: types.AddressGroup{AddressGroup: "servers"}}},
+			b: config.FirewallRule{Destination: types.AddressPort{Range: types.AddressRange{
+				Start: netip.MustParseAddr("10.0.0.20"), End: netip.MustParseAddr("10.0.0.30"),
+			}}},
+			want: false,
+		},
+		{
+			name: "unknown group never covers",
+			a:    config.FirewallRule{Destination: types.AddressPort{Group: types.AddressGroup{NetworkGroup: "missing"}}},
+			b:    config.FirewallRule{Destination: types.AddressPort{Group: types.AddressGroup{NetworkGroup: "missing"}}},
+			want: false,
+		},
+		{
+			name: "port range covers port in range",
+			a:    config.FirewallRule{Protocol: types.ProtocolTCP, Destination: types.AddressPort{Group: types.AddressGroup{PortGroup: "high"}}},
+			b:    config.FirewallRule{Protocol: types.ProtocolTCP, Destination: types.AddressPort{Port: types.Ports{"8080"}}},
+			want: true,
+		},
+		{
+			name: "port group does not cover a port outside it",
+			a:    config.FirewallRule{Protocol: types.ProtocolTCP, Destination: types.AddressPort{Group: types.AddressGroup{PortGroup: "web"}}},
+			b:    config.FirewallRule{Protocol: types.ProtocolTCP, Destination: types.AddressPort{Port: types.Ports{"22"}}},
+			want: false,
+		},
+		{
+			name: "service names are compared by name",
+			a:    config.FirewallRule{Protocol: types.ProtocolTCP, Destination: types.AddressPort{Port: types.Ports{"ssh", "http"}}},
+			b:    config.FirewallRule{Protocol: types.ProtocolTCP, Destination: types.AddressPort{Port: types.Ports{"ssh"}}},
+			want: true,
+		},
+		{
+			name: "rule without state covers established",
+			a:    config.FirewallRule{},
+			b:    config.FirewallRule{Established: types.Enable},
+			want: true,
+		},
+		{
+			name: "established does not cover new",
+			a:    config.FirewallRule{Established: types.Enable},
+			b:    config.FirewallRule{New: types.Enable},
+			want: false,
+		},
+		{
+			name: "established does not cover a rule without state",
+			a:    config.FirewallRule{Established: types.Enable},
+			b:    config.FirewallRule{},
+			want: false,
+		},
+		{
+			name: "established and related cover established",
+			a:    config.FirewallRule{Established: types.Enable, Related: types.Enable},
+			b:    config.FirewallRule{Established: types.Enable},
+			want: true,
+		},
+		{
+			name: "rate limited rule never covers",
+			a:    config.FirewallRule{Limit: &config.FirewallLimit{}},
+			b:    config.FirewallRule{},
+			want: false,
+		},
+		{
+			name: "tcp flags must be the same",
+			a:    config.FirewallRule{Protocol: types.ProtocolTCP, TCPFlags: "SYN,!ACK"},
+			b:    config.FirewallRule{Protocol: types.ProtocolTCP},
+			want: false,
+		},
+		{
+			name: "mac address must be the same, ignoring case",
+			a:    config.FirewallRule{Source: types.AddressPort{MACAddress: "aa:bb:cc:dd:ee:ff"}},
+			b:    config.FirewallRule{Source: types.AddressPort{MACAddress: "AA:BB:CC:DD:EE:FF"}},
+			want: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := covers(groups, tt.a, tt.b); got != tt.want {
+				t.Errorf("covers() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeRanges(t *testing.T) {
+	r := func(start, end string) addressRange {
+		return addressRange{start: netip.MustParseAddr(start), end: netip.MustParseAddr(end)}
+	}
+
+	tests := []struct {
+		name   string
+		ranges []addressRange
+		want   []addressRange
+	}{
+		{
+			name:   "empty",
+			ranges: nil,
+			want:   nil,
+		},
+		{
+			name:   "separate ranges are sorted",
+			ranges: []addressRange{r("10.0.0.20", "10.0.0.30"), r("10.0.0.1", "10.0.0.5")},
+			want:   []addressRange{r("10.0.0.1", "10.0.0.5"), r("10.0.0.20", "10.0.0.30")},
+		},
+		{
+			name:   "overlapping ranges are joined",
+			ranges: []addressRange{r("10.0.0.1", "10.0.0.10"), r("10.0.0.5", "10.0.0.20")},
+			want:   []addressRange{r("10.0.0.1", "10.0.0.20")},
+		},
+		{
+			name:   "adjacent ranges are joined",
+			ranges: []addressRange{r("10.0.0.0", "10.0.0.127"), r("10.0.0.128", "10.0.0.255")},
+			want:   []addressRange{r("10.0.0.0", "10.0.0.255")},
+		},
+		{
+			name:   "contained range is absorbed",
+			ranges: []addressRange{r("10.0.0.0", "10.0.0.255"), r("10.0.0.5", "10.0.0.6")},
+			want:   []addressRange{r("10.0.0.0", "10.0.0.255")},
+		},
+		{
+			name:   "range ending at the last address",
+			ranges: []addressRange{r("255.255.255.0", "255.255.255.255"), r("255.255.255.255", "255.255.255.255")},
+			want:   []addressRange{r("255.255.255.0", "255.255.255.255")},
+		},
+		{
+			name:   "ipv4 and ipv6 are not joined",
+			ranges: []addressRange{r("255.255.255.255", "255.255.255.255"), r("::", "::1")},
+			want:   []addressRange{r("255.255.255.255", "255.255.255.255"), r("::", "::1")},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mergeRanges(tt.ranges); !slices.Equal(got, tt.want) {
+				t.Errorf("mergeRanges() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrefixRange(t *testing.T) {
+	tests := []struct {
+		prefix string
+		start  string
+		end    string
+	}{
+		{prefix: "10.0.0.0/24", start: "10.0.0.0", end: "10.0.0.255"},
+		{prefix: "10.0.0.77/24", start: "10.0.0.0", end: "10.0.0.255"},
+		{prefix: "10.0.0.64/26", start: "10.0.0.64", end: "10.0.0.127"},
+		{prefix: "10.0.0.1/32", start: "10.0.0.1", end: "10.0.0.1"},
+		{prefix: "0.0.0.0/0", start: "0.0.0.0", end: "255.255.255.255"},
+		{prefix: "2001:db8::/64", start: "2001:db8::", end: "2001:db8::ffff:ffff:ffff:ffff"},
+		{prefix: "2001:db8::1/128", start: "2001:db8::1", end: "2001:db8::1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.prefix, func(t *testing.T) {
+			got := prefixRange(netip.MustParsePrefix(tt.prefix))
+			want := addressRange{start: netip.MustParseAddr(tt.start), end: netip.MustParseAddr(tt.end)}
+			if got != want {
+				t.Errorf("prefixRange(%s) = %v, want %v", tt.prefix, got, want)
+			}
+		})
+	}
+}
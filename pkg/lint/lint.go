@@ -0,0 +1,299 @@
+// Package lint finds firewall rules that can never match or don't do anything, and zones that are never used
+package lint
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/cmmarslender/edgefig/pkg/config"
+	"github.com/cmmarslender/edgefig/pkg/translate"
+	"github.com/cmmarslender/edgefig/pkg/types"
+)
+
+// Severity is how serious a finding is
+type Severity string
+
+const (
+	// SeverityError is a rule that can never do what it says, such as a drop rule after a broader accept
+	SeverityError Severity = "error"
+	// SeverityWarning is config that has no effect, such as a duplicate rule, and can be removed
+	SeverityWarning Severity = "warning"
+)
+
+// Checks that produce findings
+const (
+	CheckShadowed       = "shadowed"
+	CheckRedundant      = "redundant"
+	CheckDuplicate      = "duplicate"
+	CheckUnreachable    = "unreachable"
+	CheckEmptyGroup     = "empty-group"
+	CheckUnknownGroup   = "unknown-group"
+	CheckUnattachedZone = "unattached-zone"
+)
+
+// Finding is a single problem found in a router's firewall config
+type Finding struct {
+	Severity Severity `json:"severity"`
+	Check    string   `json:"check"`
+	Zone     string   `json:"zone"`
+	// Family is only set for zones with ip-type both, which are generated as a separate ruleset for each family
+	Family types.IPAddressType `json:"family,omitempty"`
+	// Rule is the number the rule has on the router, or 0 for the zone itself
+	Rule    uint32 `json:"rule,omitempty"`
+	Message string `json:"message"`
+}
+
+// String returns the finding as a single line
+func (f Finding) String() string {
+	zone := f.Zone
+	if f.Family != "" {
+		zone = fmt.Sprintf("%s (%s)", zone, f.Family)
+	}
+	return fmt.Sprintf("%s [%s] %s: %s", f.Severity, f.Check, zone, f.Message)
+}
+
+// AtLeast returns true if the severity is as serious as the other severity
+func (s Severity) AtLeast(other Severity) bool {
+	return s == SeverityError || s == other
+}
+
+// zoneRule is a rule in a zone with its number on the router, along with the shared ruleset it was included from
+type zoneRule struct {
+	position int
+	number   uint32
+	ruleset  string
+	rule     config.FirewallRule
+}
+
+// String describes the rule for findings, such as rule 30 "allow ssh" (from ruleset common)
+func (r zoneRule) String() string {
+	description := fmt.Sprintf("rule %d", r.number)
+	if r.rule.Description != "" {
+		description = fmt.Sprintf("%s %q", description, r.rule.Description)
+	}
+	if r.ruleset != "" {
+		description = fmt.Sprintf("%s (from ruleset %s)", description, r.ruleset)
+	}
+	return description
+}
+
+// Firewall checks the firewall zones of the router, returning findings in the order the zones and rules are listed
+// Rules are numbered the same way they are generated, and zones with ip-type both are checked once per family
+func Firewall(cfg *config.Config, router config.Router) ([]Finding, error) {
+	findings := []Finding{}
+	groups := router.Firewall.Groups
+
+	for _, zone := range router.Firewall.Zones {
+		rulesets, err := rulesetNames(cfg, zone)
+		if err != nil {
+			return nil, err
+		}
+
+		families := translate.ZoneFamilies(zone)
+		checkedGroups := map[int]bool{}
+		for _, family := range families {
+			familyRules, err := translate.ZoneRules(cfg, zone, family)
+			if err != nil {
+				return nil, err
+			}
+
+			var rules []zoneRule
+			var zoneFindings []Finding
+			for _, familyRule := range familyRules {
+				rule := zoneRule{
+					position: familyRule.Position,
+					number:   familyRule.Number,
+					ruleset:  rulesets[familyRule.Position-1],
+					rule:     familyRule.Rule,
+				}
+				rules = append(rules, rule)
+
+				// Rules that are in both families only need their groups checked once
+				if !checkedGroups[rule.position] {
+					checkedGroups[rule.position] = true
+					zoneFindings = append(zoneFindings, groupFindings(zone.Name, groups, rule)...)
+				}
+			}
+			zoneFindings = append(zoneFindings, ruleFindings(zone.Name, groups, rules)...)
+			slices.SortStableFunc(zoneFindings, func(a, b Finding) int { return int(a.Rule) - int(b.Rule) })
+			if len(families) > 1 {
+				for idx := range zoneFindings {
+					zoneFindings[idx].Family = family
+				}
+			}
+			findings = append(findings, zoneFindings...)
+		}
+
+		if !attached(router.Firewall, zone) {
+			findings = append(findings, Finding{
+				Severity: SeverityWarning,
+				Check:    CheckUnattachedZone,
+				Zone:     zone.Name,
+				Message:  "zone is not attached to any interface (in, out or local) or used by the zone policy",
+			})
+		}
+	}
+
+	return findings, nil
+}
+
+// rulesetNames returns the shared ruleset each of the zone's rules is included from, in the order the router evaluates
+// them, with an empty name for the zone's own rules
+func rulesetNames(cfg *config.Config, zone config.FirewallZone) ([]string, error) {
+	var names []string
+	add := func(ruleset string, rules []config.FirewallRule) {
+		for range rules {
+			names = append(names, ruleset)
+		}
+	}
+
+	for _, rulesetName := range zone.IncludeBefore {
+		ruleset, err := cfg.GetFirewallRulesetByName(rulesetName)
+		if err != nil {
+			return nil, fmt.Errorf("firewall zone %s: %w", zone.Name, err)
+		}
+		add(ruleset.Name, ruleset.Rules)
+	}
+	add("", zone.Rules)
+	for _, rulesetName := range zone.IncludeAfter {
+		ruleset, err := cfg.GetFirewallRulesetByName(rulesetName)
+		if err != nil {
+			return nil, fmt.Errorf("firewall zone %s: %w", zone.Name, err)
+		}
+		add(ruleset.Name, ruleset.Rules)
+	}
+
+	return names, nil
+}
+
+// ruleFindings compares each enabled rule with the enabled rules before it
+// Only the first earlier rule that covers a rule is reported, and everything after an unconditional rule is
+// reported once as unreachable
+func ruleFindings(zoneName string, groups config.FirewallGroups, rules []zoneRule) []Finding {
+	var findings []Finding
+	var earlier []zoneRule
+
+	for _, current := range rules {
+		if current.rule.Disable {
+			continue
+		}
+
+		if len(earlier) > 0 && unconditional(earlier[len(earlier)-1].rule) {
+			last := earlier[len(earlier)-1]
+			findings = append(findings, Finding{
+				Severity: SeverityError,
+				Check:    CheckUnreachable,
+				Zone:     zoneName,
+				Rule:     current.number,
+				Message:  fmt.Sprintf("%s and every rule after it are unreachable, %s matches all traffic with action %s", current, last, last.rule.Action),
+			})
+			break
+		}
+
+		for _, prev := range earlier {
+			if !covers(groups, prev.rule, current.rule) {
+				continue
+			}
+
+			finding := Finding{Zone: zoneName, Rule: current.number}
+			switch {
+			case prev.rule.Action != current.rule.Action:
+				finding.Severity = SeverityError
+				finding.Check = CheckShadowed
+				finding.Message = fmt.Sprintf("%s never matches, %s matches the same traffic first with action %s instead of %s", current, prev, prev.rule.Action, current.rule.Action)
+			case covers(groups, current.rule, prev.rule):
+				finding.Severity = SeverityWarning
+				finding.Check = CheckDuplicate
+				finding.Message = fmt.Sprintf("%s is a duplicate of %s", current, prev)
+			default:
+				finding.Severity = SeverityWarning
+				finding.Check = CheckRedundant
+				finding.Message = fmt.Sprintf("%s never matches, %s already %ss the same traffic", current, prev, prev.rule.Action)
+			}
+			findings = append(findings, finding)
+			break
+		}
+
+		earlier = append(earlier, current)
+	}
+
+	return findings
+}
+
+// groupFindings reports groups used by the rule that don't exist or have no members
+// A rule using an empty group never matches
+func groupFindings(zoneName string, groups config.FirewallGroups, rule zoneRule) []Finding {
+	var findings []Finding
+	check := func(kind, name string, found bool, members int) {
+		switch {
+		case name == "":
+		case !found:
+			findings = append(findings, Finding{
+				Severity: SeverityError,
+				Check:    CheckUnknownGroup,
+				Zone:     zoneName,
+				Rule:     rule.number,
+				Message:  fmt.Sprintf("%s uses %s %s, which does not exist", rule, kind, name),
+			})
+		case members == 0:
+			findings = append(findings, Finding{
+				Severity: SeverityWarning,
+				Check:    CheckEmptyGroup,
+				Zone:     zoneName,
+				Rule:     rule.number,
+				Message:  fmt.Sprintf("%s never matches, %s %s has no members", rule, kind, name),
+			})
+		}
+	}
+
+	for _, ap := range []types.AddressPort{rule.rule.Source, rule.rule.Destination} {
+		ref := ap.Group
+		found, members := addressGroupMembers(groups.AddressGroups, ref.AddressGroup)
+		check("address-group", ref.AddressGroup, found, members)
+		found, members = addressGroupMembers(groups.IPv6AddressGroups, ref.IPv6AddressGroup)
+		check("ipv6-address-group", ref.IPv6AddressGroup, found, members)
+		found, members = networkGroupMembers(groups.NetworkGroups, ref.NetworkGroup)
+		check("network-group", ref.NetworkGroup, found, members)
+		found, members = networkGroupMembers(groups.IPv6NetworkGroups, ref.IPv6NetworkGroup)
+		check("ipv6-network-group", ref.IPv6NetworkGroup, found, members)
+		idx := slices.IndexFunc(groups.PortGroups, func(group config.PortGroup) bool { return group.Name == ref.PortGroup })
+		if idx == -1 {
+			check("port-group", ref.PortGroup, false, 0)
+		} else {
+			check("port-group", ref.PortGroup, true, len(groups.PortGroups[idx].Ports))
+		}
+	}
+
+	return findings
+}
+
+func addressGroupMembers(groups []config.AddressGroup, name string) (bool, int) {
+	idx := slices.IndexFunc(groups, func(group config.AddressGroup) bool { return group.Name == name })
+	if idx == -1 {
+		return false, 0
+	}
+	return true, len(groups[idx].Addresses)
+}
+
+func networkGroupMembers(groups []config.NetworkGroup, name string) (bool, int) {
+	idx := slices.IndexFunc(groups, func(group config.NetworkGroup) bool { return group.Name == name })
+	if idx == -1 {
+		return false, 0
+	}
+	return true, len(groups[idx].Networks)
+}
+
+// attached returns true if the zone is assigned to an interface, or used as a ruleset by the zone policy
+func attached(firewall config.Firewall, zone config.FirewallZone) bool {
+	if len(zone.In) > 0 || len(zone.Out) > 0 || len(zone.Local) > 0 {
+		return true
+	}
+	for _, policyZone := range firewall.ZonePolicy {
+		for _, from := range policyZone.From {
+			if from.Firewall == zone.Name || from.IPv6Firewall == zone.Name {
+				return true
+			}
+		}
+	}
+	return false
+}
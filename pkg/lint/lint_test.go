@@ -0,0 +1,92 @@
+package lint
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/cmmarslender/edgefig/pkg/config"
+	"github.com/cmmarslender/edgefig/pkg/types"
+)
+
+func TestFirewall(t *testing.T) {
+	acceptAll := config.FirewallRule{Action: "accept", Description: "accept all"}
+	ssh := config.FirewallRule{Action: "drop", Description: "ssh", Protocol: types.ProtocolTCP, Destination: types.AddressPort{Port: types.Ports{"22"}}}
+	lan := config.FirewallRule{Action: "accept", Description: "lan", Source: types.AddressPort{Prefix: netip.MustParsePrefix("10.0.0.0/24")}}
+	lanHost := config.FirewallRule{Action: "drop", Description: "lan host", Source: types.AddressPort{Address: netip.MustParseAddr("10.0.0.5")}}
+	lan6 := config.FirewallRule{Action: "accept", Description: "lan6", Source: types.AddressPort{Prefix: netip.MustParsePrefix("2001:db8::/64")}}
+	lan6Host := config.FirewallRule{Action: "drop", Description: "lan6 host", Source: types.AddressPort{Address: netip.MustParseAddr("2001:db8::5")}}
+
+	cfg := &config.Config{
+		FirewallRulesets: []config.FirewallRuleset{
+			{Name: "common", Rules: []config.FirewallRule{ssh}},
+		},
+	}
+
+	type want struct {
+		check  string
+		family types.IPAddressType
+		rule   uint32
+	}
+	tests := []struct {
+		name string
+		zone config.FirewallZone
+		want []want
+	}{
+		{
+			name: "automatic numbers",
+			zone: config.FirewallZone{Name: "WAN_IN", In: []string{"eth0"}, Rules: []config.FirewallRule{lan, lanHost}},
+			want: []want{{check: CheckShadowed, rule: 20}},
+		},
+		{
+			name: "explicit numbers",
+			zone: config.FirewallZone{Name: "WAN_IN", In: []string{"eth0"}, Rules: []config.FirewallRule{
+				withNumber(lan, 100), withNumber(lanHost, 150),
+			}},
+			want: []want{{check: CheckShadowed, rule: 150}},
+		},
+		{
+			name: "included rules shift the zone's rules down",
+			zone: config.FirewallZone{Name: "WAN_IN", In: []string{"eth0"}, IncludeBefore: []string{"common"}, Rules: []config.FirewallRule{acceptAll, lan}},
+			want: []want{{check: CheckUnreachable, rule: 30}},
+		},
+		{
+			name: "dual stack zones are numbered per family",
+			zone: config.FirewallZone{Name: "WAN_IN", IPType: types.IPAddressTypeBoth, In: []string{"eth0"}, Rules: []config.FirewallRule{
+				lan, lan6, lanHost, lan6Host,
+			}},
+			want: []want{
+				{check: CheckShadowed, family: types.IPAddressTypeV4, rule: 20},
+				{check: CheckShadowed, family: types.IPAddressTypeV6, rule: 20},
+			},
+		},
+		{
+			name: "unattached zone",
+			zone: config.FirewallZone{Name: "SPARE", Rules: []config.FirewallRule{lan}},
+			want: []want{{check: CheckUnattachedZone}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := config.Router{Name: "router01", Firewall: config.Firewall{Zones: []config.FirewallZone{tt.zone}}}
+			findings, err := Firewall(cfg, router)
+			if err != nil {
+				t.Fatalf("Firewall() returned error: %v", err)
+			}
+			if len(findings) != len(tt.want) {
+				t.Fatalf("Firewall() = %v, want %d findings", findings, len(tt.want))
+			}
+			for idx, finding := range findings {
+				got := want{check: finding.Check, family: finding.Family, rule: finding.Rule}
+				if got != tt.want[idx] {
+					t.Errorf("finding %d = %+v (%s), want %+v", idx, got, finding, tt.want[idx])
+				}
+			}
+		})
+	}
+}
+
+func withNumber(rule config.FirewallRule, number uint32) config.FirewallRule {
+	rule.Number = number
+	return rule
+}
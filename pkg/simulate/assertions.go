@@ -0,0 +1,83 @@
+package simulate
+
+import (
+	"bytes"
+	"fmt"
+	"net/netip"
+	"os"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/cmmarslender/edgefig/pkg/types"
+)
+
+// AssertionFile is a file of firewall assertions, run by edgefig test
+type AssertionFile struct {
+	Assertions []Assertion `yaml:"assertions"`
+}
+
+// Assertion is a packet and the action the router is expected to take on it
+type Assertion struct {
+	Name     string         `yaml:"name"`
+	Router   string         `yaml:"router"`
+	In       string         `yaml:"in"`
+	Out      string         `yaml:"out"`
+	Source   netip.Addr     `yaml:"src"`
+	Dest     netip.Addr     `yaml:"dst"`
+	Protocol types.Protocol `yaml:"proto"`
+	SPort    uint16         `yaml:"sport"`
+	DPort    uint16         `yaml:"dport"`
+	State    string         `yaml:"state"`
+	Expect   string         `yaml:"expect"`
+}
+
+// LoadAssertions loads and validates the assertions in the file
+func LoadAssertions(path string) ([]Assertion, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading assertions file: %w", err)
+	}
+
+	// Unknown keys are rejected, so a typo like "destination" instead of "dst" fails instead of leaving a field empty
+	file := &AssertionFile{}
+	decoder := yaml.NewDecoder(bytes.NewReader(contents))
+	decoder.KnownFields(true)
+	err = decoder.Decode(file)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing assertions file: %w", err)
+	}
+
+	for idx, assertion := range file.Assertions {
+		if assertion.Name == "" {
+			return nil, fmt.Errorf("assertion %d: name is required", idx+1)
+		}
+		if assertion.Router == "" {
+			return nil, fmt.Errorf("assertion %q: router is required", assertion.Name)
+		}
+		switch assertion.Expect {
+		case ActionAccept, ActionDrop, ActionReject:
+		default:
+			return nil, fmt.Errorf("assertion %q: expect must be accept, drop, or reject", assertion.Name)
+		}
+		packet := assertion.Packet()
+		if err := packet.Validate(); err != nil {
+			return nil, fmt.Errorf("assertion %q: %w", assertion.Name, err)
+		}
+	}
+
+	return file.Assertions, nil
+}
+
+// Packet returns the packet the assertion is about
+func (a Assertion) Packet() Packet {
+	return Packet{
+		InInterface:     a.In,
+		OutInterface:    a.Out,
+		Source:          a.Source,
+		Destination:     a.Dest,
+		Protocol:        a.Protocol,
+		SourcePort:      a.SPort,
+		DestinationPort: a.DPort,
+		State:           a.State,
+	}
+}
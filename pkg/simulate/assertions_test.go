@@ -0,0 +1,92 @@
+package simulate
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadAssertions(t *testing.T) {
+	tests := []struct {
+		name     string
+		contents string
+		want     int
+		wantErr  bool
+	}{
+		{
+			name: "valid",
+			contents: `assertions:
+  - name: ssh from lan
+    router: home
+    in: eth1
+    src: 192.168.1.10
+    dst: 192.168.1.1
+    proto: tcp
+    dport: 22
+    expect: accept
+`,
+			want: 1,
+		},
+		{
+			name: "unknown key",
+			contents: `assertions:
+  - name: ssh from lan
+    router: home
+    in: eth1
+    src: 192.168.1.10
+    destination: 192.168.1.1
+    proto: tcp
+    expect: accept
+`,
+			wantErr: true,
+		},
+		{
+			name: "missing router",
+			contents: `assertions:
+  - name: ssh from lan
+    in: eth1
+    src: 192.168.1.10
+    dst: 192.168.1.1
+    proto: tcp
+    expect: accept
+`,
+			wantErr: true,
+		},
+		{
+			name: "unknown expect",
+			contents: `assertions:
+  - name: ssh from lan
+    router: home
+    in: eth1
+    src: 192.168.1.10
+    dst: 192.168.1.1
+    proto: tcp
+    expect: allow
+`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "assertions.yaml")
+			if err := os.WriteFile(path, []byte(tt.contents), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			assertions, err := LoadAssertions(path)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("LoadAssertions() = %+v, expected an error", assertions)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadAssertions() returned error: %v", err)
+			}
+			if len(assertions) != tt.want {
+				t.Errorf("LoadAssertions() returned %d assertions, want %d", len(assertions), tt.want)
+			}
+		})
+	}
+}
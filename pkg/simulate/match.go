@@ -0,0 +1,245 @@
+package simulate
+
+import (
+	"fmt"
+	"net/netip"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/cmmarslender/edgefig/pkg/edgeconfig"
+	"github.com/cmmarslender/edgefig/pkg/types"
+)
+
+// matchRule returns true if the packet matches every criteria of the firewall rule
+// Criteria that depend on more than a single packet, like rate limits, can't be evaluated and are assumed to match
+func (s *simulation) matchRule(rule edgeconfig.FirewallRule, packet Packet) (bool, error) {
+	matched, err := s.matchPacket(rule.Protocol, rule.Source, rule.Destination, packet)
+	if err != nil || !matched {
+		return false, err
+	}
+
+	if !matchState(rule.State, packet.State) {
+		return false, nil
+	}
+
+	var assumed []string
+	if rule.Source.MACAddress != "" {
+		assumed = append(assumed, "source mac-address")
+	}
+	if rule.ICMP != nil || rule.ICMPv6 != nil {
+		assumed = append(assumed, "icmp type")
+	}
+	if rule.TCP != nil {
+		assumed = append(assumed, "tcp flags")
+	}
+	if rule.Fragment != nil {
+		assumed = append(assumed, "fragment")
+	}
+	if rule.IPsec != nil {
+		assumed = append(assumed, "ipsec")
+	}
+	if rule.Limit != nil {
+		assumed = append(assumed, "limit")
+	}
+	if rule.Recent != nil {
+		assumed = append(assumed, "recent")
+	}
+	if rule.Time != nil {
+		assumed = append(assumed, "time")
+	}
+	for _, match := range assumed {
+		s.warn(fmt.Sprintf("rule %d: %s match was assumed to match", rule.Number, match))
+	}
+
+	return true, nil
+}
+
+// matchPacket returns true if the packet matches the protocol, source and destination of a firewall or NAT rule
+func (s *simulation) matchPacket(protocol types.Protocol, source, destination types.AddressPort, packet Packet) (bool, error) {
+	if !matchProtocol(protocol, packet.Protocol) {
+		return false, nil
+	}
+
+	matched, err := s.matchAddressPort(source, packet.Source, packet.SourcePort, packet.Protocol)
+	if err != nil || !matched {
+		return false, err
+	}
+
+	return s.matchAddressPort(destination, packet.Destination, packet.DestinationPort, packet.Protocol)
+}
+
+// matchProtocol returns true if the rule's protocol includes the packet's protocol
+func matchProtocol(rule, packet types.Protocol) bool {
+	switch rule {
+	case "", types.ProtocolAll:
+		return true
+	case types.ProtocolTCPUDP:
+		return packet == types.ProtocolTCP || packet == types.ProtocolUDP
+	default:
+		return rule == packet
+	}
+}
+
+// matchState returns true if the packet's state is enabled in the rule, or the rule doesn't match on state
+func matchState(rule edgeconfig.FirewallRuleState, state string) bool {
+	if rule == (edgeconfig.FirewallRuleState{}) {
+		return true
+	}
+
+	switch state {
+	case StateNew:
+		return bool(rule.New)
+	case StateEstablished:
+		return bool(rule.Established)
+	case StateRelated:
+		return bool(rule.Related)
+	case StateInvalid:
+		return bool(rule.Invalid)
+	}
+	return false
+}
+
+// matchAddressPort returns true if the address and port match every criteria set on the source or destination
+func (s *simulation) matchAddressPort(ap types.AddressPort, addr netip.Addr, port uint16, protocol types.Protocol) (bool, error) {
+	if ap.Address.IsValid() && ap.Address != addr {
+		return false, nil
+	}
+	if ap.Prefix.IsValid() && !ap.Prefix.Masked().Contains(addr) {
+		return false, nil
+	}
+	if ap.Range.Start.IsValid() && !inRange(addr, ap.Range.Start, ap.Range.End) {
+		return false, nil
+	}
+
+	groups := s.router.Firewall.Group
+	if name := ap.Group.AddressGroup; name != "" {
+		idx := slices.IndexFunc(groups.AddressGroups, func(group edgeconfig.AddressGroup) bool { return group.Name == name })
+		if idx == -1 {
+			return false, fmt.Errorf("unknown address-group %s", name)
+		}
+		if !inGroupAddresses(addr, groups.AddressGroups[idx].Addresses) {
+			return false, nil
+		}
+	}
+	if name := ap.Group.IPv6AddressGroup; name != "" {
+		idx := slices.IndexFunc(groups.IPv6AddressGroups, func(group edgeconfig.IPv6AddressGroup) bool { return group.Name == name })
+		if idx == -1 {
+			return false, fmt.Errorf("unknown ipv6-address-group %s", name)
+		}
+		if !inGroupAddresses(addr, groups.IPv6AddressGroups[idx].Addresses) {
+			return false, nil
+		}
+	}
+	if name := ap.Group.NetworkGroup; name != "" {
+		idx := slices.IndexFunc(groups.NetworkGroups, func(group edgeconfig.NetworkGroup) bool { return group.Name == name })
+		if idx == -1 {
+			return false, fmt.Errorf("unknown network-group %s", name)
+		}
+		if !inNetworks(addr, groups.NetworkGroups[idx].Networks) {
+			return false, nil
+		}
+	}
+	if name := ap.Group.IPv6NetworkGroup; name != "" {
+		idx := slices.IndexFunc(groups.IPv6NetworkGroups, func(group edgeconfig.IPv6NetworkGroup) bool { return group.Name == name })
+		if idx == -1 {
+			return false, fmt.Errorf("unknown ipv6-network-group %s", name)
+		}
+		if !inNetworks(addr, groups.IPv6NetworkGroups[idx].Networks) {
+			return false, nil
+		}
+	}
+
+	if len(ap.Port) > 0 {
+		if !protocol.HasPorts() {
+			return false, nil
+		}
+		matched, err := inPorts(port, ap.Port)
+		if err != nil || !matched {
+			return false, err
+		}
+	}
+	if name := ap.Group.PortGroup; name != "" {
+		idx := slices.IndexFunc(groups.PortGroups, func(group edgeconfig.PortGroup) bool { return group.Name == name })
+		if idx == -1 {
+			return false, fmt.Errorf("unknown port-group %s", name)
+		}
+		if !protocol.HasPorts() {
+			return false, nil
+		}
+		matched, err := inPorts(port, groups.PortGroups[idx].Ports)
+		if err != nil || !matched {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+// inRange returns true if the address is between start and end, inclusive
+func inRange(addr, start, end netip.Addr) bool {
+	return addr.Compare(start) >= 0 && addr.Compare(end) <= 0
+}
+
+// inGroupAddresses returns true if the address is one of the group's addresses or within one of its ranges
+func inGroupAddresses(addr netip.Addr, members []types.GroupAddress) bool {
+	for _, member := range members {
+		if !member.End.IsValid() && member.Start == addr {
+			return true
+		}
+		if member.End.IsValid() && inRange(addr, member.Start, member.End) {
+			return true
+		}
+	}
+	return false
+}
+
+// inNetworks returns true if any of the networks contain the address
+func inNetworks(addr netip.Addr, networks []netip.Prefix) bool {
+	for _, network := range networks {
+		if network.Masked().Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// inPorts returns true if the port is one of the ports, within one of the ranges, or is a named service
+func inPorts(port uint16, ports []types.Port) (bool, error) {
+	for _, p := range ports {
+		if start, end, isRange := strings.Cut(string(p), "-"); isRange && !strings.ContainsAny(string(p), "abcdefghijklmnopqrstuvwxyz") {
+			startNum, err := strconv.ParseUint(start, 10, 16)
+			if err != nil {
+				return false, fmt.Errorf("invalid port range %s", p)
+			}
+			endNum, err := strconv.ParseUint(end, 10, 16)
+			if err != nil {
+				return false, fmt.Errorf("invalid port range %s", p)
+			}
+			if uint64(port) >= startNum && uint64(port) <= endNum {
+				return true, nil
+			}
+			continue
+		}
+
+		num, err := portNumber(p)
+		if err != nil {
+			return false, err
+		}
+		if num == port {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// portNumber returns the number of a single port or service name
+func portNumber(port types.Port) (uint16, error) {
+	if num, err := strconv.ParseUint(string(port), 10, 16); err == nil {
+		return uint16(num), nil
+	}
+	if num, ok := services[string(port)]; ok {
+		return num, nil
+	}
+	return 0, fmt.Errorf("unknown service name %s, use the port number instead", port)
+}
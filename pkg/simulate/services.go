@@ -0,0 +1,49 @@
+package simulate
+
+// services maps common service names from /etc/services to their port, so rules using service names can be simulated
+var services = map[string]uint16{
+	"ftp-data":      20,
+	"ftp":           21,
+	"ssh":           22,
+	"telnet":        23,
+	"smtp":          25,
+	"domain":        53,
+	"bootps":        67,
+	"bootpc":        68,
+	"tftp":          69,
+	"http":          80,
+	"www":           80,
+	"kerberos":      88,
+	"pop3":          110,
+	"sunrpc":        111,
+	"ntp":           123,
+	"netbios-ns":    137,
+	"netbios-dgm":   138,
+	"netbios-ssn":   139,
+	"imap2":         143,
+	"snmp":          161,
+	"snmp-trap":     162,
+	"bgp":           179,
+	"ldap":          389,
+	"https":         443,
+	"microsoft-ds":  445,
+	"isakmp":        500,
+	"syslog":        514,
+	"submission":    587,
+	"ipp":           631,
+	"ldaps":         636,
+	"rsync":         873,
+	"imaps":         993,
+	"pop3s":         995,
+	"openvpn":       1194,
+	"l2tp":          1701,
+	"pptp":          1723,
+	"radius":        1812,
+	"radius-acct":   1813,
+	"mysql":         3306,
+	"ms-wbt-server": 3389,
+	"ipsec-nat-t":   4500,
+	"sip":           5060,
+	"postgresql":    5432,
+	"http-alt":      8080,
+}
@@ -0,0 +1,481 @@
+// Package simulate evaluates a packet against a router's translated firewall and NAT config,
+// so firewall intent can be checked without a router
+package simulate
+
+import (
+	"fmt"
+	"net/netip"
+	"slices"
+	"strings"
+
+	"github.com/cmmarslender/edgefig/pkg/edgeconfig"
+	"github.com/cmmarslender/edgefig/pkg/types"
+)
+
+// Connection states a packet can be in
+const (
+	StateNew         = "new"
+	StateEstablished = "established"
+	StateRelated     = "related"
+	StateInvalid     = "invalid"
+)
+
+// Actions in a verdict
+const (
+	ActionAccept = "accept"
+	ActionDrop   = "drop"
+	ActionReject = "reject"
+	ActionDNAT   = "dnat"
+)
+
+// Stages of the router a packet passes through
+const (
+	StageDNAT  = "dnat"
+	StageIn    = "in"
+	StageLocal = "local"
+	StageOut   = "out"
+	StageZone  = "zone-policy"
+)
+
+// Packet is a single packet arriving on an interface of the router
+type Packet struct {
+	InInterface string `json:"in_interface"`
+	// OutInterface is optional, and is looked up from connected networks and static routes when not set
+	OutInterface    string         `json:"out_interface,omitempty"`
+	Source          netip.Addr     `json:"source"`
+	Destination     netip.Addr     `json:"destination"`
+	Protocol        types.Protocol `json:"protocol"`
+	SourcePort      uint16         `json:"source_port,omitempty"`
+	DestinationPort uint16         `json:"destination_port,omitempty"`
+	State           string         `json:"state"`
+}
+
+// Validate checks the packet is complete, and fills in the default state of new
+func (p *Packet) Validate() error {
+	if p.InInterface == "" {
+		return fmt.Errorf("the interface the packet arrives on is required")
+	}
+	if !p.Source.IsValid() || !p.Destination.IsValid() {
+		return fmt.Errorf("source and destination addresses are required")
+	}
+	if p.Source.Is4() != p.Destination.Is4() {
+		return fmt.Errorf("source %s and destination %s must be the same address family", p.Source, p.Destination)
+	}
+
+	switch p.Protocol {
+	case "":
+		return fmt.Errorf("protocol is required")
+	case types.ProtocolAll, types.ProtocolTCPUDP:
+		return fmt.Errorf("a packet must have a single protocol, not %s", p.Protocol)
+	}
+	if !p.Protocol.HasPorts() && (p.SourcePort != 0 || p.DestinationPort != 0) {
+		return fmt.Errorf("ports can only be set for tcp and udp packets")
+	}
+
+	switch p.State {
+	case "":
+		p.State = StateNew
+	case StateNew, StateEstablished, StateRelated, StateInvalid:
+	default:
+		return fmt.Errorf("unknown state %s, expected new, established, related, or invalid", p.State)
+	}
+
+	return nil
+}
+
+// family returns the address family of the packet
+func (p Packet) family() types.IPAddressType {
+	if p.Source.Is4() {
+		return types.IPAddressTypeV4
+	}
+	return types.IPAddressTypeV6
+}
+
+// Step is a single decision made while the packet passed through the router
+type Step struct {
+	Stage   string `json:"stage"`
+	Ruleset string `json:"ruleset,omitempty"`
+	// Rule is the number of the matching rule, or 0 when the default action of the ruleset applied
+	Rule        uint32 `json:"rule,omitempty"`
+	Description string `json:"description,omitempty"`
+	Action      string `json:"action"`
+}
+
+// String returns a one line summary of the step
+func (s Step) String() string {
+	var where []string
+	where = append(where, s.Stage)
+	if s.Ruleset != "" {
+		where = append(where, s.Ruleset)
+	}
+	switch {
+	case s.Rule != 0:
+		where = append(where, fmt.Sprintf("rule %d", s.Rule))
+	case s.Ruleset != "":
+		where = append(where, "default-action")
+	}
+	summary := fmt.Sprintf("%s: %s", strings.Join(where, " "), s.Action)
+	if s.Description != "" {
+		summary = fmt.Sprintf("%s (%s)", summary, s.Description)
+	}
+	return summary
+}
+
+// Verdict is the outcome of simulating a packet
+type Verdict struct {
+	Allowed bool   `json:"allowed"`
+	Action  string `json:"action"`
+	// Packet is the packet after any destination NAT
+	Packet Packet `json:"packet"`
+	Steps  []Step `json:"steps"`
+	// Warnings list the matches the simulator could not evaluate and assumed matched, such as rate limits
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// Simulate passes the packet through the router's destination NAT, then its firewall rulesets and zone policy
+// The first ruleset that drops or rejects the packet decides the verdict
+func Simulate(rc *edgeconfig.Router, packet Packet) (*Verdict, error) {
+	if err := packet.Validate(); err != nil {
+		return nil, err
+	}
+
+	s := &simulation{router: rc, verdict: &Verdict{Action: ActionAccept, Steps: []Step{}}}
+
+	inFirewall, err := s.findFirewall(packet.InInterface)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.destinationNAT(&packet); err != nil {
+		return nil, err
+	}
+	s.verdict.Packet = packet
+
+	local := s.isLocal(packet.Destination)
+	var outFirewall *edgeconfig.InterfaceFirewallAssignment
+	if !local {
+		if packet.OutInterface == "" {
+			packet.OutInterface = s.route(packet.Destination)
+			s.verdict.Packet.OutInterface = packet.OutInterface
+		}
+		if packet.OutInterface == "" {
+			return nil, fmt.Errorf("no connected network or static route contains %s, set the interface the packet leaves on", packet.Destination)
+		}
+		assignment, err := s.findFirewall(packet.OutInterface)
+		if err != nil {
+			return nil, err
+		}
+		outFirewall = &assignment
+	}
+
+	if local {
+		if err := s.evaluate(StageLocal, inFirewall.Local, packet); err != nil {
+			return nil, err
+		}
+	} else {
+		if err := s.evaluate(StageIn, inFirewall.In, packet); err != nil {
+			return nil, err
+		}
+		if outFirewall != nil && s.allowed() {
+			if err := s.evaluate(StageOut, outFirewall.Out, packet); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if s.allowed() {
+		if err := s.zonePolicy(packet, local); err != nil {
+			return nil, err
+		}
+	}
+
+	s.verdict.Allowed = s.allowed()
+	return s.verdict, nil
+}
+
+// simulation holds the state of a single packet's trip through the router
+type simulation struct {
+	router  *edgeconfig.Router
+	verdict *Verdict
+}
+
+// allowed returns true while no stage has dropped or rejected the packet
+func (s *simulation) allowed() bool {
+	return s.verdict.Action == ActionAccept
+}
+
+func (s *simulation) warn(warning string) {
+	if !slices.Contains(s.verdict.Warnings, warning) {
+		s.verdict.Warnings = append(s.verdict.Warnings, warning)
+	}
+}
+
+// findFirewall returns the rulesets assigned to the interface
+// Rulesets are only assigned to ethernet interfaces, so traffic on a vif (eth1.10) is an error rather than a verdict
+// that passes it through no rulesets at all
+func (s *simulation) findFirewall(name string) (edgeconfig.InterfaceFirewallAssignment, error) {
+	parent, _, _ := strings.Cut(name, ".")
+	for _, iface := range s.router.Interfaces.Interfaces {
+		if iface.Name != parent {
+			continue
+		}
+		if parent == name {
+			return iface.Firewall, nil
+		}
+		for _, vlan := range iface.VLANs {
+			if fmt.Sprintf("%s.%d", iface.Name, vlan.ID) == name {
+				return edgeconfig.InterfaceFirewallAssignment{}, fmt.Errorf("can't simulate traffic on vif %s, firewall rulesets are only assigned to ethernet ports", name)
+			}
+		}
+	}
+	return edgeconfig.InterfaceFirewallAssignment{}, fmt.Errorf("unknown interface %s", name)
+}
+
+// interfaceAddresses returns the addresses on each interface and vif, keyed by interface name
+func (s *simulation) interfaceAddresses() map[string][]netip.Prefix {
+	addresses := map[string][]netip.Prefix{}
+	for _, iface := range s.router.Interfaces.Interfaces {
+		addresses[iface.Name] = append(addresses[iface.Name], iface.Address...)
+		for _, vlan := range iface.VLANs {
+			if vlan.Address.IsValid() {
+				name := fmt.Sprintf("%s.%d", iface.Name, vlan.ID)
+				addresses[name] = append(addresses[name], vlan.Address)
+			}
+		}
+	}
+	return addresses
+}
+
+// isLocal returns true if the address belongs to the router
+func (s *simulation) isLocal(addr netip.Addr) bool {
+	for _, prefixes := range s.interfaceAddresses() {
+		for _, prefix := range prefixes {
+			if prefix.Addr() == addr {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// route returns the interface the router sends the address out of, using the most specific connected network or
+// static route in the main table, where a connected network wins over a static route of the same length
+// A static route leaves through its next-hop interface, or the interface with a connected network containing the
+// next-hop
+func (s *simulation) route(addr netip.Addr) string {
+	best, bestBits := s.connected(addr)
+
+	var bestDistance uint8
+	for _, route := range s.router.Protocols.Static.Routes {
+		if !route.Route.Masked().Contains(addr) || route.Route.Bits() < bestBits {
+			continue
+		}
+		if route.Route.Bits() == bestBits && route.NextHop.Distance >= bestDistance {
+			continue
+		}
+		iface := route.NextHop.Interface
+		if iface == "" {
+			iface, _ = s.connected(route.NextHop.NextHop)
+		}
+		if iface == "" {
+			continue
+		}
+		best = iface
+		bestBits = route.Route.Bits()
+		bestDistance = route.NextHop.Distance
+	}
+	return best
+}
+
+// connected returns the interface with the most specific connected network containing the address, and the length
+// of that network, or -1 if there is none
+func (s *simulation) connected(addr netip.Addr) (string, int) {
+	best := ""
+	bestBits := -1
+	for name, prefixes := range s.interfaceAddresses() {
+		for _, prefix := range prefixes {
+			if prefix.Masked().Contains(addr) && prefix.Bits() > bestBits {
+				best = name
+				bestBits = prefix.Bits()
+			}
+		}
+	}
+	return best, bestBits
+}
+
+// destinationNAT rewrites the packet's destination with the first matching destination NAT rule
+func (s *simulation) destinationNAT(packet *Packet) error {
+	if !packet.Destination.Is4() {
+		return nil
+	}
+
+	rules := slices.Clone(s.router.Service.NAT.Dest)
+	slices.SortStableFunc(rules, func(a, b edgeconfig.NatRule) int {
+		return int(a.Number) - int(b.Number)
+	})
+
+	for _, rule := range rules {
+		if rule.InboundInterface != "" && rule.InboundInterface != "any" && rule.InboundInterface != packet.InInterface {
+			continue
+		}
+		matched, err := s.matchPacket(rule.Protocol, rule.Source, rule.Destination, *packet)
+		if err != nil {
+			return fmt.Errorf("nat rule %d: %w", rule.Number, err)
+		}
+		if !matched {
+			continue
+		}
+
+		if rule.InsideAddress.Address.IsValid() {
+			packet.Destination = rule.InsideAddress.Address
+		}
+		if len(rule.InsideAddress.Port) == 1 {
+			port, err := portNumber(rule.InsideAddress.Port[0])
+			if err == nil {
+				packet.DestinationPort = port
+			}
+		}
+		s.verdict.Steps = append(s.verdict.Steps, Step{
+			Stage:       StageDNAT,
+			Rule:        rule.Number,
+			Description: rule.Name,
+			Action:      ActionDNAT,
+		})
+		return nil
+	}
+
+	return nil
+}
+
+// evaluate runs the packet through the ruleset assigned to an interface, if there is one for the packet's family
+func (s *simulation) evaluate(stage string, assignment edgeconfig.InterfaceFirewallZone, packet Packet) error {
+	name := assignment.Name
+	if packet.family() == types.IPAddressTypeV6 {
+		name = assignment.V6Name
+	}
+	if name == "" {
+		return nil
+	}
+
+	step, err := s.ruleset(name, packet)
+	if err != nil {
+		return err
+	}
+	step.Stage = stage
+	s.record(step)
+	return nil
+}
+
+// record adds the step to the verdict, with drop and reject deciding the verdict
+func (s *simulation) record(step Step) {
+	s.verdict.Steps = append(s.verdict.Steps, step)
+	if step.Action != ActionAccept {
+		s.verdict.Action = step.Action
+	}
+}
+
+// ruleset evaluates the rules of the named ruleset in order, returning the first match or the default action
+func (s *simulation) ruleset(name string, packet Packet) (Step, error) {
+	namePrefix := ""
+	if packet.family() == types.IPAddressTypeV6 {
+		namePrefix = "ipv6-"
+	}
+
+	idx := slices.IndexFunc(s.router.Firewall.Zones, func(zone edgeconfig.FirewallZone) bool {
+		return zone.Name == name && zone.NamePrefix == namePrefix
+	})
+	if idx == -1 {
+		return Step{}, fmt.Errorf("unknown %sname firewall ruleset %s", namePrefix, name)
+	}
+	zone := s.router.Firewall.Zones[idx]
+
+	rules := slices.Clone(zone.Rules)
+	slices.SortStableFunc(rules, func(a, b edgeconfig.FirewallRule) int {
+		return int(a.Number) - int(b.Number)
+	})
+
+	for _, rule := range rules {
+		if rule.Disable {
+			continue
+		}
+		matched, err := s.matchRule(rule, packet)
+		if err != nil {
+			return Step{}, fmt.Errorf("firewall ruleset %s rule %d: %w", name, rule.Number, err)
+		}
+		if matched {
+			return Step{Ruleset: name, Rule: rule.Number, Description: rule.Description, Action: rule.Action}, nil
+		}
+	}
+
+	defaultAction := zone.DefaultAction
+	if defaultAction == "" {
+		defaultAction = ActionDrop
+	}
+	return Step{Ruleset: name, Action: defaultAction}, nil
+}
+
+// zonePolicy applies the zone policy to traffic between zones
+// Traffic within a zone is allowed, and traffic between a zone and an interface without a zone is dropped
+func (s *simulation) zonePolicy(packet Packet, local bool) error {
+	zones := s.router.ZonePolicy.Zones
+	if len(zones) == 0 {
+		return nil
+	}
+
+	zoneOf := func(iface string) *edgeconfig.PolicyZone {
+		for idx := range zones {
+			if slices.Contains(zones[idx].Interfaces, iface) {
+				return &zones[idx]
+			}
+		}
+		return nil
+	}
+
+	from := zoneOf(packet.InInterface)
+	var to *edgeconfig.PolicyZone
+	if local {
+		idx := slices.IndexFunc(zones, func(zone edgeconfig.PolicyZone) bool { return bool(zone.LocalZone) })
+		if idx == -1 {
+			// Without a local zone, traffic to the router is only filtered by interface rulesets
+			return nil
+		}
+		to = &zones[idx]
+	} else {
+		to = zoneOf(packet.OutInterface)
+	}
+
+	switch {
+	case from == nil && to == nil:
+		return nil
+	case from == nil || to == nil:
+		s.record(Step{Stage: StageZone, Description: "traffic between a zone and an interface without a zone", Action: ActionDrop})
+		return nil
+	case from.Name == to.Name:
+		s.record(Step{Stage: StageZone, Description: fmt.Sprintf("traffic within zone %s", from.Name), Action: ActionAccept})
+		return nil
+	}
+
+	defaultStep := Step{Stage: StageZone, Description: fmt.Sprintf("zone %s default-action for traffic from %s", to.Name, from.Name), Action: to.DefaultAction}
+	idx := slices.IndexFunc(to.From, func(f edgeconfig.PolicyZoneFrom) bool { return f.Zone == from.Name })
+	if idx == -1 {
+		s.record(defaultStep)
+		return nil
+	}
+
+	name := to.From[idx].Firewall.Name
+	if packet.family() == types.IPAddressTypeV6 {
+		name = to.From[idx].Firewall.V6Name
+	}
+	if name == "" {
+		s.record(defaultStep)
+		return nil
+	}
+
+	step, err := s.ruleset(name, packet)
+	if err != nil {
+		return err
+	}
+	step.Stage = StageZone
+	s.record(step)
+	return nil
+}
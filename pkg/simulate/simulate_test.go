@@ -0,0 +1,467 @@
+package simulate
+
+import (
+	"net/netip"
+	"slices"
+	"testing"
+
+	"github.com/cmmarslender/edgefig/pkg/edgeconfig"
+	"github.com/cmmarslender/edgefig/pkg/types"
+)
+
+// testRouter is a router with a wan (eth0), a lan (eth1) with a vif, and a static route to a network behind the lan
+func testRouter() *edgeconfig.Router {
+	return &edgeconfig.Router{
+		Firewall: edgeconfig.Firewall{
+			Group: edgeconfig.FirewallGroups{
+				AddressGroups: []edgeconfig.AddressGroup{
+					{Name: "admins", Addresses: []types.GroupAddress{
+						{Start: netip.MustParseAddr("192.168.1.10")},
+						{Start: netip.MustParseAddr("192.168.1.20"), End: netip.MustParseAddr("192.168.1.29")},
+					}},
+				},
+				NetworkGroups: []edgeconfig.NetworkGroup{
+					{Name: "lan", Networks: []netip.Prefix{netip.MustParsePrefix("192.168.1.0/24")}},
+				},
+				PortGroups: []edgeconfig.PortGroup{
+					{Name: "web", Ports: []types.Port{"http", "443", "8000-8099"}},
+				},
+			},
+			Zones: []edgeconfig.FirewallZone{
+				{
+					Name:          "WAN_IN",
+					DefaultAction: ActionDrop,
+					Rules: []edgeconfig.FirewallRule{
+						{Number: 10, Action: ActionAccept, State: edgeconfig.FirewallRuleState{Established: types.Enable, Related: types.Enable}},
+						{Number: 20, Action: ActionAccept, Protocol: types.ProtocolTCP, Destination: types.AddressPort{Address: netip.MustParseAddr("192.168.1.50"), Port: types.Ports{"443"}}},
+					},
+				},
+				{
+					Name:          "WAN_LOCAL",
+					DefaultAction: ActionDrop,
+					Rules: []edgeconfig.FirewallRule{
+						{Number: 10, Action: ActionAccept, State: edgeconfig.FirewallRuleState{Established: types.Enable, Related: types.Enable}},
+					},
+				},
+				{
+					Name:          "LAN_LOCAL",
+					DefaultAction: ActionAccept,
+					Rules: []edgeconfig.FirewallRule{
+						{Number: 10, Action: ActionReject, Protocol: types.ProtocolTCP, Destination: types.AddressPort{Port: types.Ports{"ssh"}}, Source: types.AddressPort{Group: types.AddressGroup{NetworkGroup: "lan"}}},
+						{Number: 5, Action: ActionAccept, Protocol: types.ProtocolTCP, Destination: types.AddressPort{Port: types.Ports{"ssh"}}, Source: types.AddressPort{Group: types.AddressGroup{AddressGroup: "admins"}}},
+					},
+				},
+			},
+		},
+		Interfaces: edgeconfig.Interfaces{
+			Interfaces: []edgeconfig.Interface{
+				{
+					Name:    "eth0",
+					Address: []netip.Prefix{netip.MustParsePrefix("203.0.113.2/24")},
+					Firewall: edgeconfig.InterfaceFirewallAssignment{
+						In:    edgeconfig.InterfaceFirewallZone{Name: "WAN_IN"},
+						Local: edgeconfig.InterfaceFirewallZone{Name: "WAN_LOCAL"},
+					},
+				},
+				{
+					Name:    "eth1",
+					Address: []netip.Prefix{netip.MustParsePrefix("192.168.1.1/24")},
+					Firewall: edgeconfig.InterfaceFirewallAssignment{
+						Local: edgeconfig.InterfaceFirewallZone{Name: "LAN_LOCAL"},
+					},
+					VLANs: []edgeconfig.VLAN{
+						{ID: 10, Address: netip.MustParsePrefix("192.168.10.1/24")},
+					},
+				},
+			},
+		},
+		Protocols: edgeconfig.RouterProtocols{
+			Static: edgeconfig.StaticProtocol{
+				Routes: []edgeconfig.StaticRoute{
+					{Route: netip.MustParsePrefix("10.50.0.0/16"), NextHop: edgeconfig.NextHop{NextHop: netip.MustParseAddr("192.168.1.254")}},
+				},
+			},
+		},
+		Service: edgeconfig.RouterServices{
+			NAT: edgeconfig.NatService{
+				Dest: []edgeconfig.NatRule{
+					{
+						Number:           10,
+						Name:             "web server",
+						InboundInterface: "eth0",
+						Protocol:         types.ProtocolTCP,
+						Destination:      types.AddressPort{Port: types.Ports{"8443"}},
+						InsideAddress:    types.AddressPort{Address: netip.MustParseAddr("192.168.1.50"), Port: types.Ports{"443"}},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestSimulate(t *testing.T) {
+	tests := []struct {
+		name    string
+		packet  Packet
+		wantErr bool
+		action  string
+		out     string
+		stages  []string
+		rules   []uint32
+	}{
+		{
+			name:   "new connection from the wan to the router is dropped",
+			packet: Packet{InInterface: "eth0", Source: netip.MustParseAddr("198.51.100.5"), Destination: netip.MustParseAddr("203.0.113.2"), Protocol: types.ProtocolTCP, DestinationPort: 22},
+			action: ActionDrop,
+			stages: []string{StageLocal},
+			rules:  []uint32{0},
+		},
+		{
+			name:   "established connection from the wan to the router is accepted",
+			packet: Packet{InInterface: "eth0", Source: netip.MustParseAddr("198.51.100.5"), Destination: netip.MustParseAddr("203.0.113.2"), Protocol: types.ProtocolTCP, DestinationPort: 22, State: StateEstablished},
+			action: ActionAccept,
+			stages: []string{StageLocal},
+			rules:  []uint32{10},
+		},
+		{
+			name:   "port forward is translated then accepted",
+			packet: Packet{InInterface: "eth0", Source: netip.MustParseAddr("198.51.100.5"), Destination: netip.MustParseAddr("203.0.113.2"), Protocol: types.ProtocolTCP, DestinationPort: 8443},
+			action: ActionAccept,
+			out:    "eth1",
+			stages: []string{StageDNAT, StageIn},
+			rules:  []uint32{10, 20},
+		},
+		{
+			name:   "rules are evaluated in number order",
+			packet: Packet{InInterface: "eth1", Source: netip.MustParseAddr("192.168.1.25"), Destination: netip.MustParseAddr("192.168.1.1"), Protocol: types.ProtocolTCP, DestinationPort: 22},
+			action: ActionAccept,
+			stages: []string{StageLocal},
+			rules:  []uint32{5},
+		},
+		{
+			name:   "reject rule decides the verdict",
+			packet: Packet{InInterface: "eth1", Source: netip.MustParseAddr("192.168.1.30"), Destination: netip.MustParseAddr("192.168.1.1"), Protocol: types.ProtocolTCP, DestinationPort: 22},
+			action: ActionReject,
+			stages: []string{StageLocal},
+			rules:  []uint32{10},
+		},
+		{
+			name:   "vif address is local to the router",
+			packet: Packet{InInterface: "eth1", Source: netip.MustParseAddr("192.168.1.30"), Destination: netip.MustParseAddr("192.168.10.1"), Protocol: types.ProtocolUDP, DestinationPort: 53},
+			action: ActionAccept,
+			stages: []string{StageLocal},
+			rules:  []uint32{0},
+		},
+		{
+			name:    "out interface on a connected vif is an error",
+			packet:  Packet{InInterface: "eth1", Source: netip.MustParseAddr("192.168.1.30"), Destination: netip.MustParseAddr("192.168.10.5"), Protocol: types.ProtocolICMP},
+			wantErr: true,
+		},
+		{
+			name:    "in interface on a vif is an error",
+			packet:  Packet{InInterface: "eth1.10", Source: netip.MustParseAddr("192.168.10.5"), Destination: netip.MustParseAddr("192.168.1.30"), Protocol: types.ProtocolICMP},
+			wantErr: true,
+		},
+		{
+			name:   "out interface is found from a static route's next-hop",
+			packet: Packet{InInterface: "eth0", Source: netip.MustParseAddr("198.51.100.5"), Destination: netip.MustParseAddr("10.50.1.1"), Protocol: types.ProtocolUDP},
+			action: ActionDrop,
+			out:    "eth1",
+			stages: []string{StageIn},
+			rules:  []uint32{0},
+		},
+		{
+			name:    "destination without a route is an error",
+			packet:  Packet{InInterface: "eth1", Source: netip.MustParseAddr("192.168.1.30"), Destination: netip.MustParseAddr("8.8.8.8"), Protocol: types.ProtocolUDP},
+			wantErr: true,
+		},
+		{
+			name:   "out interface can be given",
+			packet: Packet{InInterface: "eth1", OutInterface: "eth0", Source: netip.MustParseAddr("192.168.1.30"), Destination: netip.MustParseAddr("8.8.8.8"), Protocol: types.ProtocolUDP},
+			action: ActionAccept,
+			out:    "eth0",
+			stages: []string{},
+		},
+		{
+			name:    "unknown in interface",
+			packet:  Packet{InInterface: "eth9", Source: netip.MustParseAddr("192.168.1.30"), Destination: netip.MustParseAddr("192.168.1.1"), Protocol: types.ProtocolTCP},
+			wantErr: true,
+		},
+		{
+			name:    "ports on an icmp packet",
+			packet:  Packet{InInterface: "eth1", Source: netip.MustParseAddr("192.168.1.30"), Destination: netip.MustParseAddr("192.168.1.1"), Protocol: types.ProtocolICMP, DestinationPort: 22},
+			wantErr: true,
+		},
+		{
+			name:    "mixed address families",
+			packet:  Packet{InInterface: "eth1", Source: netip.MustParseAddr("192.168.1.30"), Destination: netip.MustParseAddr("2001:db8::1"), Protocol: types.ProtocolTCP},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verdict, err := Simulate(testRouter(), tt.packet)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Simulate() = %+v, expected an error", verdict)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Simulate() returned error: %v", err)
+			}
+
+			if verdict.Action != tt.action {
+				t.Errorf("action = %s, want %s (steps %v)", verdict.Action, tt.action, verdict.Steps)
+			}
+			if verdict.Allowed != (tt.action == ActionAccept) {
+				t.Errorf("allowed = %t for action %s", verdict.Allowed, verdict.Action)
+			}
+			if verdict.Packet.OutInterface != tt.out {
+				t.Errorf("out interface = %q, want %q", verdict.Packet.OutInterface, tt.out)
+			}
+
+			var stages []string
+			var rules []uint32
+			for _, step := range verdict.Steps {
+				stages = append(stages, step.Stage)
+				rules = append(rules, step.Rule)
+			}
+			if !slices.Equal(stages, tt.stages) {
+				t.Errorf("stages = %v, want %v", stages, tt.stages)
+			}
+			if tt.rules != nil && !slices.Equal(rules, tt.rules) {
+				t.Errorf("rules = %v, want %v", rules, tt.rules)
+			}
+		})
+	}
+}
+
+func TestSimulateZonePolicy(t *testing.T) {
+	router := testRouter()
+	router.Interfaces.Interfaces = append(router.Interfaces.Interfaces, edgeconfig.Interface{
+		Name:    "eth2",
+		Address: []netip.Prefix{netip.MustParsePrefix("192.168.20.1/24")},
+	})
+	router.ZonePolicy.Zones = []edgeconfig.PolicyZone{
+		{Name: "wan", DefaultAction: ActionDrop, Interfaces: []string{"eth0"}},
+		{Name: "lan", DefaultAction: ActionDrop, Interfaces: []string{"eth1"}, From: []edgeconfig.PolicyZoneFrom{
+			{Zone: "wan", Firewall: edgeconfig.InterfaceFirewallZone{Name: "WAN_IN"}},
+		}},
+	}
+
+	tests := []struct {
+		name   string
+		packet Packet
+		action string
+	}{
+		{
+			name:   "ruleset between zones is applied",
+			packet: Packet{InInterface: "eth0", Source: netip.MustParseAddr("198.51.100.5"), Destination: netip.MustParseAddr("192.168.1.50"), Protocol: types.ProtocolTCP, DestinationPort: 443},
+			action: ActionAccept,
+		},
+		{
+			name:   "zone without a ruleset for the source zone uses its default action",
+			packet: Packet{InInterface: "eth1", OutInterface: "eth0", Source: netip.MustParseAddr("192.168.1.30"), Destination: netip.MustParseAddr("8.8.8.8"), Protocol: types.ProtocolUDP},
+			action: ActionDrop,
+		},
+		{
+			name:   "traffic within a zone is allowed",
+			packet: Packet{InInterface: "eth1", Source: netip.MustParseAddr("192.168.1.30"), Destination: netip.MustParseAddr("10.50.0.1"), Protocol: types.ProtocolUDP},
+			action: ActionAccept,
+		},
+		{
+			name:   "traffic to an interface without a zone is dropped",
+			packet: Packet{InInterface: "eth1", Source: netip.MustParseAddr("192.168.1.30"), Destination: netip.MustParseAddr("192.168.20.5"), Protocol: types.ProtocolUDP},
+			action: ActionDrop,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verdict, err := Simulate(router, tt.packet)
+			if err != nil {
+				t.Fatalf("Simulate() returned error: %v", err)
+			}
+			if verdict.Action != tt.action {
+				t.Errorf("action = %s, want %s (steps %v)", verdict.Action, tt.action, verdict.Steps)
+			}
+		})
+	}
+}
+
+func TestMatchAddressPort(t *testing.T) {
+	s := &simulation{router: testRouter(), verdict: &Verdict{}}
+
+	tests := []struct {
+		name     string
+		ap       types.AddressPort
+		addr     string
+		port     uint16
+		protocol types.Protocol
+		want     bool
+		wantErr  bool
+	}{
+		{
+			name: "empty criteria matches anything",
+			ap:   types.AddressPort{},
+			addr: "10.0.0.1",
+			want: true,
+		},
+		{
+			name: "address matches",
+			ap:   types.AddressPort{Address: netip.MustParseAddr("10.0.0.1")},
+			addr: "10.0.0.1",
+			want: true,
+		},
+		{
+			name: "address does not match",
+			ap:   types.AddressPort{Address: netip.MustParseAddr("10.0.0.1")},
+			addr: "10.0.0.2",
+			want: false,
+		},
+		{
+			name: "prefix with host bits set",
+			ap:   types.AddressPort{Prefix: netip.MustParsePrefix("10.0.0.1/24")},
+			addr: "10.0.0.200",
+			want: true,
+		},
+		{
+			name: "range end is inclusive",
+			ap:   types.AddressPort{Range: types.AddressRange{Start: netip.MustParseAddr("10.0.0.1"), End: netip.MustParseAddr("10.0.0.5")}},
+			addr: "10.0.0.5",
+			want: true,
+		},
+		{
+			name: "address group range",
+			ap:   types.AddressPort{Group: types.AddressGroup{AddressGroup: "admins"}},
+			addr: "192.168.1.25",
+			want: true,
+		},
+		{
+			name: "address group outside its members",
+			ap:   types.AddressPort{Group: types.AddressGroup{AddressGroup: "admins"}},
+			addr: "192.168.1.11",
+			want: false,
+		},
+		{
+			name:    "unknown address group",
+			ap:      types.AddressPort{Group: types.AddressGroup{AddressGroup: "missing"}},
+			addr:    "192.168.1.10",
+			wantErr: true,
+		},
+		{
+			name: "network group",
+			ap:   types.AddressPort{Group: types.AddressGroup{NetworkGroup: "lan"}},
+			addr: "192.168.1.99",
+			want: true,
+		},
+		{
+			name: "every criteria must match",
+			ap:   types.AddressPort{Group: types.AddressGroup{NetworkGroup: "lan"}, Address: netip.MustParseAddr("192.168.1.10")},
+			addr: "192.168.1.99",
+			want: false,
+		},
+		{
+			name:     "port matches",
+			ap:       types.AddressPort{Port: types.Ports{"22"}},
+			addr:     "10.0.0.1",
+			port:     22,
+			protocol: types.ProtocolTCP,
+			want:     true,
+		},
+		{
+			name:     "port never matches a protocol without ports",
+			ap:       types.AddressPort{Port: types.Ports{"22"}},
+			addr:     "10.0.0.1",
+			protocol: types.ProtocolICMP,
+			want:     false,
+		},
+		{
+			name:     "port group with a service name",
+			ap:       types.AddressPort{Group: types.AddressGroup{PortGroup: "web"}},
+			addr:     "10.0.0.1",
+			port:     80,
+			protocol: types.ProtocolTCP,
+			want:     true,
+		},
+		{
+			name:     "port group outside its ports",
+			ap:       types.AddressPort{Group: types.AddressGroup{PortGroup: "web"}},
+			addr:     "10.0.0.1",
+			port:     8100,
+			protocol: types.ProtocolTCP,
+			want:     false,
+		},
+		{
+			name:     "unknown port group",
+			ap:       types.AddressPort{Group: types.AddressGroup{PortGroup: "missing"}},
+			addr:     "10.0.0.1",
+			port:     80,
+			protocol: types.ProtocolTCP,
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			protocol := tt.protocol
+			if protocol == "" {
+				protocol = types.ProtocolTCP
+			}
+			got, err := s.matchAddressPort(tt.ap, netip.MustParseAddr(tt.addr), tt.port, protocol)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("matchAddressPort() = %t, expected an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("matchAddressPort() returned error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("matchAddressPort() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInPorts(t *testing.T) {
+	tests := []struct {
+		name    string
+		port    uint16
+		ports   []types.Port
+		want    bool
+		wantErr bool
+	}{
+		{name: "single port", port: 22, ports: []types.Port{"22"}, want: true},
+		{name: "different port", port: 23, ports: []types.Port{"22"}, want: false},
+		{name: "range start", port: 8000, ports: []types.Port{"8000-8099"}, want: true},
+		{name: "range end", port: 8099, ports: []types.Port{"8000-8099"}, want: true},
+		{name: "past range end", port: 8100, ports: []types.Port{"8000-8099"}, want: false},
+		{name: "service name", port: 443, ports: []types.Port{"https"}, want: true},
+		{name: "any of several", port: 443, ports: []types.Port{"80", "443"}, want: true},
+		{name: "unknown service name with a dash", port: 22, ports: []types.Port{"not-a-service"}, wantErr: true},
+		{name: "invalid range", port: 22, ports: []types.Port{"10-70000"}, wantErr: true},
+		{name: "no ports", port: 22, ports: nil, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := inPorts(tt.port, tt.ports)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("inPorts(%d, %v) = %t, expected an error", tt.port, tt.ports, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("inPorts(%d, %v) returned error: %v", tt.port, tt.ports, err)
+			}
+			if got != tt.want {
+				t.Errorf("inPorts(%d, %v) = %t, want %t", tt.port, tt.ports, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package translate
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/cmmarslender/edgefig/pkg/config"
+	"github.com/cmmarslender/edgefig/pkg/edgeconfig"
+	"github.com/cmmarslender/edgefig/pkg/types"
+)
+
+// translateConntrack converts the conntrack settings, returning nil when nothing is set so the router keeps its defaults
+func translateConntrack(conntrack config.Conntrack) (*edgeconfig.Conntrack, error) {
+	timeouts := conntrack.Timeouts
+	edgeConntrack := &edgeconfig.Conntrack{
+		ExpectTableSize: conntrack.ExpectTableSize,
+		HashSize:        conntrack.HashSize,
+		TableSize:       conntrack.TableSize,
+		Timeout: edgeconfig.ConntrackTimeout{
+			ICMP:  timeouts.ICMP,
+			Other: timeouts.Other,
+			TCP: edgeconfig.ConntrackTCPTimeout{
+				Close:       timeouts.TCP.Close,
+				CloseWait:   timeouts.TCP.CloseWait,
+				Established: timeouts.TCP.Established,
+				FinWait:     timeouts.TCP.FinWait,
+				LastAck:     timeouts.TCP.LastAck,
+				SynRecv:     timeouts.TCP.SynRecv,
+				SynSent:     timeouts.TCP.SynSent,
+				TimeWait:    timeouts.TCP.TimeWait,
+			},
+			UDP: edgeconfig.ConntrackUDPTimeout{
+				Other:  timeouts.UDP.Other,
+				Stream: timeouts.UDP.Stream,
+			},
+		},
+	}
+
+	modules := map[string]**edgeconfig.ConntrackModule{
+		"ftp":  &edgeConntrack.Modules.FTP,
+		"gre":  &edgeConntrack.Modules.GRE,
+		"h323": &edgeConntrack.Modules.H323,
+		"pptp": &edgeConntrack.Modules.PPTP,
+		"sip":  &edgeConntrack.Modules.SIP,
+		"tftp": &edgeConntrack.Modules.TFTP,
+	}
+	// Sorted so an unknown module always gives the same error
+	names := make([]string, 0, len(conntrack.Modules))
+	for name := range conntrack.Modules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		module, ok := modules[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown conntrack module %s, expected ftp, gre, h323, pptp, sip, or tftp", name)
+		}
+		// Modules are enabled unless disabled, so only disabled modules are written to the config
+		if !conntrack.Modules[name] {
+			*module = &edgeconfig.ConntrackModule{Disable: types.KeyWhenEnabled(true)}
+		}
+	}
+
+	if *edgeConntrack == (edgeconfig.Conntrack{}) {
+		return nil, nil
+	}
+	return edgeConntrack, nil
+}
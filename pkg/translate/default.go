@@ -11,6 +11,12 @@ import (
 
 func getDefaultRouterConfig(interfaces map[string]struct{}) *edgeconfig.Router {
 	cfg := edgeconfig.Router{
+		Firewall: edgeconfig.Firewall{
+			AllPing:          types.Enable,
+			SendRedirects:    types.Enable,
+			SourceValidation: "disable",
+			SynCookies:       types.Enable,
+		},
 		Interfaces: edgeconfig.Interfaces{
 			// Edgerouters have eth0 on a static IP and eth1 on DHCP out of the box
 			// We do discovery to determine what other interfaces exist
@@ -31,7 +37,9 @@ func getDefaultRouterConfig(interfaces map[string]struct{}) *edgeconfig.Router {
 			Switches: []edgeconfig.SwitchInterface{},
 		},
 		System: edgeconfig.RouterSystem{
-			HostName: "EdgeRouter-Infinity", // @TODO this should be based on the detected router model
+			AnalyticsHandler: &edgeconfig.AnalyticsHandler{},
+			CrashHandler:     &edgeconfig.CrashHandler{},
+			HostName:         "EdgeRouter-Infinity", // @TODO this should be based on the detected router model
 			Login: edgeconfig.RouterLogin{
 				Users: []edgeconfig.User{
 					{
@@ -85,6 +93,7 @@ func getDefaultRouterConfig(interfaces map[string]struct{}) *edgeconfig.Router {
 				Port:            22,
 				ProtocolVersion: "v2",
 			},
+			UNMS: &edgeconfig.UNMSService{},
 		},
 	}
 
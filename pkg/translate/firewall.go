@@ -0,0 +1,526 @@
+package translate
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+
+	"github.com/cmmarslender/edgefig/pkg/config"
+	"github.com/cmmarslender/edgefig/pkg/edgeconfig"
+	"github.com/cmmarslender/edgefig/pkg/types"
+)
+
+// expandZoneRules returns the full ordered list of rules for a zone, with included rulesets expanded in place
+func expandZoneRules(cfg *config.Config, zone config.FirewallZone) ([]config.FirewallRule, error) {
+	var rules []config.FirewallRule
+
+	for _, rulesetName := range zone.IncludeBefore {
+		ruleset, err := cfg.GetFirewallRulesetByName(rulesetName)
+		if err != nil {
+			return nil, fmt.Errorf("firewall zone %s: %w", zone.Name, err)
+		}
+		rules = append(rules, ruleset.Rules...)
+	}
+
+	rules = append(rules, zone.Rules...)
+
+	for _, rulesetName := range zone.IncludeAfter {
+		ruleset, err := cfg.GetFirewallRulesetByName(rulesetName)
+		if err != nil {
+			return nil, fmt.Errorf("firewall zone %s: %w", zone.Name, err)
+		}
+		rules = append(rules, ruleset.Rules...)
+	}
+
+	return rules, nil
+}
+
+// translateFirewallGroups converts the configured groups, checking that every member matches the group's address family
+func translateFirewallGroups(groups config.FirewallGroups) (edgeconfig.FirewallGroups, error) {
+	var edgeGroups edgeconfig.FirewallGroups
+	names := map[string]struct{}{}
+	checkName := func(kind, name string) error {
+		if name == "" {
+			return fmt.Errorf("%s is missing a name", kind)
+		}
+		if _, ok := names[kind+" "+name]; ok {
+			return fmt.Errorf("duplicate %s %s", kind, name)
+		}
+		names[kind+" "+name] = struct{}{}
+		return nil
+	}
+
+	for _, group := range groups.AddressGroups {
+		if err := checkName("address-group", group.Name); err != nil {
+			return edgeGroups, err
+		}
+		for _, address := range group.Addresses {
+			if address.Is6() {
+				return edgeGroups, fmt.Errorf("address-group %s contains ipv6 address %s, use an ipv6-address-group instead", group.Name, address.Start.String())
+			}
+		}
+		edgeGroups.AddressGroups = append(edgeGroups.AddressGroups, edgeconfig.AddressGroup{
+			Name:        group.Name,
+			Addresses:   group.Addresses,
+			Description: group.Description,
+		})
+	}
+
+	for _, group := range groups.IPv6AddressGroups {
+		if err := checkName("ipv6-address-group", group.Name); err != nil {
+			return edgeGroups, err
+		}
+		for _, address := range group.Addresses {
+			if !address.Is6() {
+				return edgeGroups, fmt.Errorf("ipv6-address-group %s contains ipv4 address %s, use an address-group instead", group.Name, address.Start.String())
+			}
+		}
+		edgeGroups.IPv6AddressGroups = append(edgeGroups.IPv6AddressGroups, edgeconfig.IPv6AddressGroup{
+			Name:        group.Name,
+			Description: group.Description,
+			Addresses:   group.Addresses,
+		})
+	}
+
+	for _, group := range groups.NetworkGroups {
+		if err := checkName("network-group", group.Name); err != nil {
+			return edgeGroups, err
+		}
+		for _, network := range group.Networks {
+			if network.Addr().Is6() {
+				return edgeGroups, fmt.Errorf("network-group %s contains ipv6 network %s, use an ipv6-network-group instead", group.Name, network.String())
+			}
+		}
+		edgeGroups.NetworkGroups = append(edgeGroups.NetworkGroups, edgeconfig.NetworkGroup{
+			Name:        group.Name,
+			Description: group.Description,
+			Networks:    group.Networks,
+		})
+	}
+
+	for _, group := range groups.IPv6NetworkGroups {
+		if err := checkName("ipv6-network-group", group.Name); err != nil {
+			return edgeGroups, err
+		}
+		for _, network := range group.Networks {
+			if !network.Addr().Is6() {
+				return edgeGroups, fmt.Errorf("ipv6-network-group %s contains ipv4 network %s, use a network-group instead", group.Name, network.String())
+			}
+		}
+		edgeGroups.IPv6NetworkGroups = append(edgeGroups.IPv6NetworkGroups, edgeconfig.IPv6NetworkGroup{
+			Name:        group.Name,
+			Description: group.Description,
+			Networks:    group.Networks,
+		})
+	}
+
+	for _, group := range groups.PortGroups {
+		if err := checkName("port-group", group.Name); err != nil {
+			return edgeGroups, err
+		}
+		edgeGroups.PortGroups = append(edgeGroups.PortGroups, edgeconfig.PortGroup{
+			Name:        group.Name,
+			Description: group.Description,
+			Ports:       group.Ports,
+		})
+	}
+
+	return edgeGroups, nil
+}
+
+// validateGroupRefs checks that every group referenced by a rule's source or destination is defined on the router
+func validateGroupRefs(groups config.FirewallGroups, ap types.AddressPort) error {
+	ref := ap.Group
+	switch {
+	case ref.AddressGroup != "" && !hasAddressGroup(groups.AddressGroups, ref.AddressGroup):
+		return fmt.Errorf("could not find address-group %s", ref.AddressGroup)
+	case ref.IPv6AddressGroup != "" && !hasAddressGroup(groups.IPv6AddressGroups, ref.IPv6AddressGroup):
+		return fmt.Errorf("could not find ipv6-address-group %s", ref.IPv6AddressGroup)
+	case ref.NetworkGroup != "" && !hasNetworkGroup(groups.NetworkGroups, ref.NetworkGroup):
+		return fmt.Errorf("could not find network-group %s", ref.NetworkGroup)
+	case ref.IPv6NetworkGroup != "" && !hasNetworkGroup(groups.IPv6NetworkGroups, ref.IPv6NetworkGroup):
+		return fmt.Errorf("could not find ipv6-network-group %s", ref.IPv6NetworkGroup)
+	case ref.PortGroup != "" && !hasPortGroup(groups.PortGroups, ref.PortGroup):
+		return fmt.Errorf("could not find port-group %s", ref.PortGroup)
+	}
+
+	return nil
+}
+
+// validatePorts checks that ports are only matched on protocols that have them
+func validatePorts(protocol types.Protocol, aps ...types.AddressPort) error {
+	for _, ap := range aps {
+		if (len(ap.Port) > 0 || ap.Group.PortGroup != "") && !protocol.HasPorts() {
+			return fmt.Errorf("ports can only be used with protocol tcp, udp, or tcp_udp, not %q", protocol)
+		}
+	}
+	return nil
+}
+
+func hasAddressGroup(groups []config.AddressGroup, name string) bool {
+	for _, group := range groups {
+		if group.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func hasNetworkGroup(groups []config.NetworkGroup, name string) bool {
+	for _, group := range groups {
+		if group.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func hasPortGroup(groups []config.PortGroup, name string) bool {
+	for _, group := range groups {
+		if group.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// translateFirewallRule converts a single rule to its edgeconfig form
+func translateFirewallRule(rule config.FirewallRule) edgeconfig.FirewallRule {
+	_rule := edgeconfig.FirewallRule{
+		Action:      rule.Action,
+		Description: rule.Description,
+		Destination: rule.Destination,
+		Disable:     types.KeyWhenEnabled(rule.Disable),
+		Log:         rule.Log,
+		Protocol:    rule.Protocol,
+		State: edgeconfig.FirewallRuleState{
+			Established: rule.Established,
+			Invalid:     rule.Invalid,
+			New:         rule.New,
+			Related:     rule.Related,
+		},
+		Source: rule.Source,
+	}
+
+	if rule.ICMP != nil {
+		if rule.Protocol == types.ProtocolICMPv6 {
+			icmpType := rule.ICMP.TypeName
+			if rule.ICMP.Type != nil {
+				icmpType = fmt.Sprintf("%d", *rule.ICMP.Type)
+				if rule.ICMP.Code != nil {
+					icmpType = fmt.Sprintf("%s/%d", icmpType, *rule.ICMP.Code)
+				}
+			}
+			_rule.ICMPv6 = &edgeconfig.FirewallICMPv6{Type: icmpType}
+		} else {
+			_rule.ICMP = &edgeconfig.FirewallICMP{
+				Code:     rule.ICMP.Code,
+				Type:     rule.ICMP.Type,
+				TypeName: rule.ICMP.TypeName,
+			}
+		}
+	}
+	if rule.TCPFlags != "" {
+		_rule.TCP = &edgeconfig.FirewallTCP{Flags: rule.TCPFlags}
+	}
+	if rule.Limit != nil {
+		_rule.Limit = &edgeconfig.FirewallLimit{Burst: rule.Limit.Burst, Rate: rule.Limit.Rate}
+	}
+	if rule.Recent != nil {
+		_rule.Recent = &edgeconfig.FirewallRecent{Count: rule.Recent.Count, Time: rule.Recent.Time}
+	}
+	if rule.Time != nil {
+		_rule.Time = &edgeconfig.FirewallTime{
+			StartTime: fullTimeOfDay(rule.Time.StartTime),
+			StopTime:  fullTimeOfDay(rule.Time.StopTime),
+			UTC:       types.KeyWhenEnabled(rule.Time.UTC),
+			Weekdays:  strings.Join(rule.Time.Weekdays, ","),
+		}
+	}
+	if rule.Fragment != nil {
+		_rule.Fragment = &edgeconfig.FirewallRuleMatch{Match: "match-non-frag"}
+		if *rule.Fragment {
+			_rule.Fragment.Match = "match-frag"
+		}
+	}
+	if rule.IPsec != nil {
+		_rule.IPsec = &edgeconfig.FirewallRuleMatch{Match: "match-none"}
+		if *rule.IPsec {
+			_rule.IPsec.Match = "match-ipsec"
+		}
+	}
+
+	return _rule
+}
+
+var (
+	limitRateRegex = regexp.MustCompile(`^\d+/(second|minute|hour|day)$`)
+	timeOfDayRegex = regexp.MustCompile(`^([01]\d|2[0-3]):[0-5]\d(:[0-5]\d)?$`)
+	weekdays       = map[string]struct{}{"Mon": {}, "Tue": {}, "Wed": {}, "Thu": {}, "Fri": {}, "Sat": {}, "Sun": {}}
+)
+
+// fullTimeOfDay adds seconds to hh:mm times, since EdgeOS expects hh:mm:ss
+func fullTimeOfDay(timeOfDay string) string {
+	if len(timeOfDay) == len("hh:mm") {
+		return timeOfDay + ":00"
+	}
+	return timeOfDay
+}
+
+// validateRuleMatches checks the rule's extra matches are complete and make sense together
+func validateRuleMatches(rule config.FirewallRule) error {
+	if rule.ICMP != nil {
+		if rule.Protocol != types.ProtocolICMP && rule.Protocol != types.ProtocolICMPv6 {
+			return fmt.Errorf("icmp can only be matched with protocol icmp or icmpv6")
+		}
+		if (rule.ICMP.TypeName == "") == (rule.ICMP.Type == nil) {
+			return fmt.Errorf("icmp needs exactly one of type-name or type")
+		}
+		if rule.ICMP.Code != nil && rule.ICMP.Type == nil {
+			return fmt.Errorf("icmp code can only be used with type")
+		}
+	}
+
+	if rule.TCPFlags != "" && rule.Protocol != types.ProtocolTCP {
+		return fmt.Errorf("tcp-flags can only be matched with protocol tcp")
+	}
+
+	if rule.Limit != nil && !limitRateRegex.MatchString(rule.Limit.Rate) {
+		return fmt.Errorf("limit rate %q must look like 10/second, minute, hour, or day", rule.Limit.Rate)
+	}
+
+	if rule.Recent != nil && (rule.Recent.Count == 0 || rule.Recent.Time == 0) {
+		return fmt.Errorf("recent needs both count and time")
+	}
+
+	if rule.Time != nil {
+		for _, day := range rule.Time.Weekdays {
+			if _, ok := weekdays[day]; !ok {
+				return fmt.Errorf("unknown weekday %q, expected Mon, Tue, Wed, Thu, Fri, Sat, or Sun", day)
+			}
+		}
+		for _, timeOfDay := range []string{rule.Time.StartTime, rule.Time.StopTime} {
+			if timeOfDay != "" && !timeOfDayRegex.MatchString(timeOfDay) {
+				return fmt.Errorf("time %q must be hh:mm or hh:mm:ss", timeOfDay)
+			}
+		}
+		if len(rule.Time.Weekdays) == 0 && rule.Time.StartTime == "" && rule.Time.StopTime == "" {
+			return fmt.Errorf("time needs weekdays, start, or stop")
+		}
+	}
+
+	if rule.Destination.MACAddress != "" {
+		return fmt.Errorf("mac-address can only be matched on the source")
+	}
+	if rule.Source.MACAddress != "" {
+		if _, err := net.ParseMAC(rule.Source.MACAddress); err != nil {
+			return fmt.Errorf("invalid source mac-address: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// ZoneRule is a rule of a firewall zone as it is generated for one address family
+type ZoneRule struct {
+	// Position is where the rule is in the zone, counting rules included from shared rulesets, starting at 1
+	Position int
+	// Number is the rule number on the router
+	Number uint32
+	Rule   config.FirewallRule
+}
+
+// ZoneRules returns the zone's rules for the address family, including shared rulesets, numbered the way they are
+// generated. Rules without an explicit number are numbered by their final position, so included rules shift the
+// zone's rules down
+func ZoneRules(cfg *config.Config, zone config.FirewallZone, family types.IPAddressType) ([]ZoneRule, error) {
+	rules, err := expandZoneRules(cfg, zone)
+	if err != nil {
+		return nil, err
+	}
+
+	var zoneRules []ZoneRule
+	for idx, rule := range rules {
+		zoneRules = append(zoneRules, ZoneRule{Position: idx + 1, Rule: rule})
+	}
+	if zone.IPType == types.IPAddressTypeBoth {
+		zoneRules, err = rulesForFamily(zoneRules, family)
+		if err != nil {
+			return nil, fmt.Errorf("firewall zone %s: %w", zone.Name, err)
+		}
+	}
+
+	explicit := make([]uint32, len(zoneRules))
+	for idx, zoneRule := range zoneRules {
+		explicit[idx] = zoneRule.Rule.Number
+	}
+	numbers, err := numberRules(explicit, 1, maxRuleNumber)
+	if err != nil {
+		return nil, fmt.Errorf("firewall zone %s: %w", zone.Name, err)
+	}
+	for idx := range zoneRules {
+		zoneRules[idx].Number = numbers[idx]
+	}
+
+	return zoneRules, nil
+}
+
+// ZoneFamilies returns the address families the zone is generated for
+func ZoneFamilies(zone config.FirewallZone) []types.IPAddressType {
+	switch zone.IPType {
+	case types.IPAddressTypeBoth:
+		return []types.IPAddressType{types.IPAddressTypeV4, types.IPAddressTypeV6}
+	case types.IPAddressTypeV6:
+		return []types.IPAddressType{types.IPAddressTypeV6}
+	default:
+		return []types.IPAddressType{types.IPAddressTypeV4}
+	}
+}
+
+// rulesForFamily returns the rules from a dual stack zone that apply to the address family
+// Rules with addresses or address groups only apply to that address's family, and rules without any apply to both.
+// Protocol icmp is swapped for icmpv6 in the ipv6 copy of a rule, and icmpv6 rules only apply to ipv6
+func rulesForFamily(rules []ZoneRule, family types.IPAddressType) ([]ZoneRule, error) {
+	var familyRules []ZoneRule
+
+	for _, zoneRule := range rules {
+		rule := zoneRule.Rule
+		ruleFamily, err := firewallRuleFamily(rule)
+		if err != nil {
+			return nil, fmt.Errorf("rule %q: %w", rule.Description, err)
+		}
+		if ruleFamily != "" && ruleFamily != family {
+			continue
+		}
+
+		if family == types.IPAddressTypeV6 && rule.Protocol == types.ProtocolICMP {
+			// Type names mostly carry over to icmpv6, but type numbers don't
+			if rule.ICMP != nil && rule.ICMP.Type != nil {
+				return nil, fmt.Errorf("rule %q: icmp types in rules for both ipv4 and ipv6 must use type-name", rule.Description)
+			}
+			rule.Protocol = types.ProtocolICMPv6
+		}
+		zoneRule.Rule = rule
+		familyRules = append(familyRules, zoneRule)
+	}
+
+	return familyRules, nil
+}
+
+// firewallRuleFamily returns the address family a rule is specific to, or an empty type if it applies to both
+func firewallRuleFamily(rule config.FirewallRule) (types.IPAddressType, error) {
+	var family types.IPAddressType
+	if rule.Protocol == types.ProtocolICMPv6 {
+		family = types.IPAddressTypeV6
+	}
+
+	for _, ap := range []types.AddressPort{rule.Source, rule.Destination} {
+		apFamily := addressFamily(ap)
+		if apFamily == "" {
+			continue
+		}
+		if family != "" && apFamily != family {
+			return "", fmt.Errorf("mixes %s and %s", family, apFamily)
+		}
+		family = apFamily
+	}
+
+	return family, nil
+}
+
+// addressFamily returns the address family of the addresses or address groups in the source/destination
+// or an empty type if it only has ports (or nothing at all)
+func addressFamily(ap types.AddressPort) types.IPAddressType {
+	switch {
+	case ap.Address.IsValid():
+		return familyOf(ap.Address.Is6())
+	case ap.Prefix.IsValid():
+		return familyOf(ap.Prefix.Addr().Is6())
+	case ap.Range.Start.IsValid():
+		return familyOf(ap.Range.Start.Is6())
+	case ap.Group.AddressGroup != "", ap.Group.NetworkGroup != "":
+		return types.IPAddressTypeV4
+	case ap.Group.IPv6AddressGroup != "", ap.Group.IPv6NetworkGroup != "":
+		return types.IPAddressTypeV6
+	default:
+		return ""
+	}
+}
+
+func familyOf(is6 bool) types.IPAddressType {
+	if is6 {
+		return types.IPAddressTypeV6
+	}
+	return types.IPAddressTypeV4
+}
+
+// assignFirewallZone assigns the zone's ruleset for the family to the interface in the given direction (in, out, or local)
+// Interfaces that aren't ethernet ports on the router are an error, rather than being skipped and leaving the port unfiltered
+func assignFirewallZone(rc *edgeconfig.Router, ifaceName, direction string, family types.IPAddressType, zoneName string) error {
+	for ifaceIdx := range rc.Interfaces.Interfaces {
+		iface := &rc.Interfaces.Interfaces[ifaceIdx]
+		if iface.Name != ifaceName {
+			continue
+		}
+
+		var assignment *edgeconfig.InterfaceFirewallZone
+		switch direction {
+		case "in":
+			assignment = &iface.Firewall.In
+		case "out":
+			assignment = &iface.Firewall.Out
+		case "local":
+			assignment = &iface.Firewall.Local
+		default:
+			return fmt.Errorf("unknown firewall direction %s", direction)
+		}
+
+		name := &assignment.Name
+		if family == types.IPAddressTypeV6 {
+			name = &assignment.V6Name
+		}
+		if *name != "" {
+			return fmt.Errorf("interface %s already has %s firewall zone %s for %s, can't also assign %s", ifaceName, family, *name, direction, zoneName)
+		}
+		*name = zoneName
+		return nil
+	}
+
+	if interfaceExists(rc, ifaceName) {
+		return fmt.Errorf("firewall zone %s: interface %s can't have rulesets attached, only ethernet interfaces can", zoneName, ifaceName)
+	}
+	return fmt.Errorf("firewall zone %s: interface %s does not exist on the router", zoneName, ifaceName)
+}
+
+// applyFirewallOptions overrides the default router wide firewall settings with the options that are set
+func applyFirewallOptions(rc *edgeconfig.Router, options config.FirewallOptions) error {
+	for _, option := range []struct {
+		value   *bool
+		setting *types.EnableDisable
+	}{
+		{options.AllPing, &rc.Firewall.AllPing},
+		{options.BroadcastPing, &rc.Firewall.BroadcastPing},
+		{options.LogMartians, &rc.Firewall.LogMartians},
+		{options.ReceiveRedirects, &rc.Firewall.ReceiveRedirects},
+		{options.SendRedirects, &rc.Firewall.SendRedirects},
+		{options.IPSrcRoute, &rc.Firewall.IPSrcRoute},
+		{options.IPv6ReceiveRedirects, &rc.Firewall.IPv6ReceiveRedirects},
+		{options.IPv6SrcRoute, &rc.Firewall.IPv6SrcRoute},
+		{options.SynCookies, &rc.Firewall.SynCookies},
+	} {
+		if option.value != nil {
+			*option.setting = types.EnableDisable(*option.value)
+		}
+	}
+
+	switch options.SourceValidation {
+	case "":
+	case "strict", "loose", "disable":
+		rc.Firewall.SourceValidation = options.SourceValidation
+	default:
+		return fmt.Errorf("unknown firewall source-validation %s, expected strict, loose, or disable", options.SourceValidation)
+	}
+
+	return nil
+}
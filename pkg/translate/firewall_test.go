@@ -0,0 +1,84 @@
+package translate
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/cmmarslender/edgefig/pkg/config"
+	"github.com/cmmarslender/edgefig/pkg/types"
+)
+
+func TestFirewallZoneInterfaces(t *testing.T) {
+	tests := []struct {
+		name    string
+		iface   string
+		ports   []string
+		wantErr string
+	}{
+		{
+			name:  "configured interface",
+			iface: "eth2",
+			ports: []string{"eth2"},
+		},
+		{
+			name:  "port the router has but the config doesn't configure",
+			iface: "eth4",
+			ports: []string{"eth2", "eth4"},
+		},
+		{
+			name:    "port the router doesn't have",
+			iface:   "eth9",
+			ports:   []string{"eth2"},
+			wantErr: "interface eth9 does not exist on the router",
+		},
+		{
+			name:    "vif",
+			iface:   "eth2.10",
+			ports:   []string{"eth2"},
+			wantErr: "interface eth2.10 can't have rulesets attached",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vlan := config.VLAN{Name: "guest", ID: 10}
+			cfg := &config.Config{VLANs: []config.VLAN{vlan}}
+			router := config.Router{
+				Name: "router01",
+				Interfaces: map[string]config.RouterInterface{
+					"eth2": {Name: "LAN", VLANs: []string{"guest"}},
+				},
+				Firewall: config.Firewall{
+					Zones: []config.FirewallZone{
+						{Name: "LAN_IN", IPType: types.IPAddressTypeV4, DefaultAction: "drop", In: []string{tt.iface}},
+					},
+				},
+			}
+			ports := map[string]struct{}{}
+			for _, port := range tt.ports {
+				ports[port] = struct{}{}
+			}
+
+			edgecfg, err := ConfigToEdgeConfig(cfg, router, ports)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ConfigToEdgeConfig() error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ConfigToEdgeConfig() returned error: %v", err)
+			}
+
+			for _, iface := range edgecfg.Interfaces.Interfaces {
+				if iface.Name == tt.iface {
+					if iface.Firewall.In.Name != "LAN_IN" {
+						t.Errorf("%s firewall in = %q, want LAN_IN", tt.iface, iface.Firewall.In.Name)
+					}
+					return
+				}
+			}
+			t.Errorf("%s is missing from the generated interfaces", tt.iface)
+		})
+	}
+}
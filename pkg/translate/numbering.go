@@ -0,0 +1,77 @@
+package translate
+
+import (
+	"fmt"
+
+	"github.com/cmmarslender/edgefig/pkg/edgeconfig"
+)
+
+const (
+	// ruleNumberStep is the gap between automatically numbered rules, leaving room to insert rules later
+	ruleNumberStep = 10
+	// maxRuleNumber is the highest rule number EdgeOS accepts for firewall and NAT rules
+	maxRuleNumber = 9999
+	// natSourceRuleStart is the first NAT rule number used for source rules, destination rules are numbered below it
+	natSourceRuleStart = 5000
+)
+
+// numberRules assigns a rule number to each rule, keeping the explicit numbers (non-zero entries) as they are
+// Rules without a number are numbered in steps of 10 after the rule before them, as long as that stays below the next
+// explicit number. Otherwise they take the next free number. Numbers must be between first and last, and increase in
+// the order the rules are listed so the router evaluates them in the same order they are written
+func numberRules(explicit []uint32, first, last uint32) ([]uint32, error) {
+	numbers := make([]uint32, len(explicit))
+	prev := first - 1
+
+	for idx, number := range explicit {
+		if number != 0 {
+			switch {
+			case number < first || number > last:
+				return nil, fmt.Errorf("rule number %d is outside of %d-%d", number, first, last)
+			case number == prev:
+				return nil, fmt.Errorf("rule number %d is used more than once", number)
+			case number < prev:
+				return nil, fmt.Errorf("rule number %d must be higher than the rule before it (%d)", number, prev)
+			}
+			numbers[idx] = number
+			prev = number
+			continue
+		}
+
+		limit := last + 1
+		for _, next := range explicit[idx+1:] {
+			if next != 0 {
+				limit = next
+				break
+			}
+		}
+
+		number = (prev/ruleNumberStep + 1) * ruleNumberStep
+		if number >= limit {
+			number = prev + 1
+		}
+		if number >= limit {
+			return nil, fmt.Errorf("no rule number left between %d and %d for rule %d", prev, limit, idx+1)
+		}
+		numbers[idx] = number
+		prev = number
+	}
+
+	return numbers, nil
+}
+
+// numberNATRules numbers the NAT rules within their block, keeping any explicit numbers
+func numberNATRules(rules []edgeconfig.NatRule, first, last uint32) error {
+	explicit := make([]uint32, len(rules))
+	for idx, rule := range rules {
+		explicit[idx] = rule.Number
+	}
+	numbers, err := numberRules(explicit, first, last)
+	if err != nil {
+		return err
+	}
+	for idx := range rules {
+		rules[idx].Number = numbers[idx]
+	}
+	return nil
+}
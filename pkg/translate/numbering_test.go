@@ -0,0 +1,141 @@
+package translate
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestNumberRules(t *testing.T) {
+	tests := []struct {
+		name     string
+		explicit []uint32
+		first    uint32
+		last     uint32
+		want     []uint32
+		wantErr  bool
+	}{
+		{
+			name:     "no rules",
+			explicit: []uint32{},
+			first:    1,
+			last:     9999,
+			want:     []uint32{},
+		},
+		{
+			name:     "automatic numbers step by 10",
+			explicit: []uint32{0, 0, 0},
+			first:    1,
+			last:     9999,
+			want:     []uint32{10, 20, 30},
+		},
+		{
+			name:     "automatic numbers continue after an explicit number",
+			explicit: []uint32{0, 15, 0},
+			first:    1,
+			last:     9999,
+			want:     []uint32{10, 15, 20},
+		},
+		{
+			name:     "automatic number squeezed below the next explicit number",
+			explicit: []uint32{0, 0, 12},
+			first:    1,
+			last:     9999,
+			want:     []uint32{10, 11, 12},
+		},
+		{
+			name:     "explicit numbers are kept",
+			explicit: []uint32{5, 100, 101},
+			first:    1,
+			last:     9999,
+			want:     []uint32{5, 100, 101},
+		},
+		{
+			name:     "block starting above zero",
+			explicit: []uint32{0, 0},
+			first:    5000,
+			last:     9999,
+			want:     []uint32{5000, 5010},
+		},
+		{
+			name:     "last step lands on the limit",
+			explicit: []uint32{9980, 0, 0},
+			first:    1,
+			last:     9999,
+			want:     []uint32{9980, 9990, 9991},
+		},
+		{
+			name:     "no number left at the end of the block",
+			explicit: []uint32{9999, 0},
+			first:    1,
+			last:     9999,
+			wantErr:  true,
+		},
+		{
+			name:     "no number left between explicit numbers",
+			explicit: []uint32{10, 0, 11},
+			first:    1,
+			last:     9999,
+			wantErr:  true,
+		},
+		{
+			name:     "duplicate explicit number",
+			explicit: []uint32{10, 10},
+			first:    1,
+			last:     9999,
+			wantErr:  true,
+		},
+		{
+			name:     "automatic number collides with a later explicit number",
+			explicit: []uint32{0, 10},
+			first:    1,
+			last:     9999,
+			want:     []uint32{1, 10},
+		},
+		{
+			name:     "explicit numbers out of order",
+			explicit: []uint32{20, 10},
+			first:    1,
+			last:     9999,
+			wantErr:  true,
+		},
+		{
+			name:     "explicit number after a higher automatic number",
+			explicit: []uint32{0, 0, 15},
+			first:    1,
+			last:     9999,
+			want:     []uint32{10, 11, 15},
+		},
+		{
+			name:     "explicit number below the block",
+			explicit: []uint32{100},
+			first:    5000,
+			last:     9999,
+			wantErr:  true,
+		},
+		{
+			name:     "explicit number above the block",
+			explicit: []uint32{5000},
+			first:    1,
+			last:     4999,
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := numberRules(tt.explicit, tt.first, tt.last)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("numberRules(%v) = %v, expected an error", tt.explicit, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("numberRules(%v) returned error: %v", tt.explicit, err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("numberRules(%v) = %v, want %v", tt.explicit, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package translate
+
+import (
+	"fmt"
+
+	"github.com/cmmarslender/edgefig/pkg/config"
+	"github.com/cmmarslender/edgefig/pkg/edgeconfig"
+	"github.com/cmmarslender/edgefig/pkg/types"
+)
+
+// translateOffload converts the offload settings, returning nil when nothing is set so the router keeps its defaults
+func translateOffload(offload config.Offload) (*edgeconfig.Offload, error) {
+	if offload.IPv6.GRE != nil || offload.IPv6.Bonding != nil {
+		return nil, fmt.Errorf("offload ipv6 only supports forwarding, vlan and pppoe")
+	}
+
+	edgeOffload := &edgeconfig.Offload{
+		HWNAT: enableDisable(offload.HWNAT),
+		IPSec: enableDisable(offload.IPSec),
+		IPv4:  translateOffloadFamily(offload.IPv4),
+		IPv6:  translateOffloadFamily(offload.IPv6),
+	}
+
+	if *edgeOffload == (edgeconfig.Offload{}) {
+		return nil, nil
+	}
+	return edgeOffload, nil
+}
+
+func translateOffloadFamily(family config.OffloadFamily) *edgeconfig.OffloadFamily {
+	edgeFamily := &edgeconfig.OffloadFamily{
+		Bonding:    enableDisable(family.Bonding),
+		Forwarding: enableDisable(family.Forwarding),
+		GRE:        enableDisable(family.GRE),
+		PPPoE:      enableDisable(family.PPPoE),
+		VLAN:       enableDisable(family.VLAN),
+	}
+
+	if *edgeFamily == (edgeconfig.OffloadFamily{}) {
+		return nil
+	}
+	return edgeFamily
+}
+
+// enableDisable converts an optional setting, keeping nil for settings that aren't set
+func enableDisable(value *bool) *types.EnableDisable {
+	if value == nil {
+		return nil
+	}
+	setting := types.EnableDisable(*value)
+	return &setting
+}
@@ -0,0 +1,207 @@
+package translate
+
+import (
+	"fmt"
+	"net/netip"
+	"slices"
+
+	"github.com/cmmarslender/edgefig/pkg/config"
+	"github.com/cmmarslender/edgefig/pkg/edgeconfig"
+	"github.com/cmmarslender/edgefig/pkg/types"
+)
+
+// maxRoutingTable is the highest routing table number EdgeOS accepts for policy routes
+const maxRoutingTable = 250
+
+// translatePolicyRoutes adds a modify ruleset for each policy route, attached to the policy route's interfaces
+// Rules with a next hop route through a table with a default route to it, using the rule's table, or the lowest
+// table that isn't used elsewhere
+func translatePolicyRoutes(rc *edgeconfig.Router, router config.Router) error {
+	usedTables := map[uint8]struct{}{}
+	for _, route := range router.Routes {
+		if route.Table != 0 {
+			usedTables[route.Table] = struct{}{}
+		}
+	}
+	for _, policyRoute := range router.PolicyRoutes {
+		for _, rule := range policyRoute.Rules {
+			if rule.Table != 0 {
+				usedTables[rule.Table] = struct{}{}
+			}
+		}
+	}
+
+	names := map[string]struct{}{}
+	nextHopTables := map[netip.Addr]uint8{}
+	for _, policyRoute := range router.PolicyRoutes {
+		if policyRoute.Name == "" {
+			return fmt.Errorf("policy route is missing a name")
+		}
+		if _, ok := names[policyRoute.Name]; ok {
+			return fmt.Errorf("duplicate policy route %s", policyRoute.Name)
+		}
+		names[policyRoute.Name] = struct{}{}
+
+		modify := edgeconfig.FirewallModify{
+			Name:        policyRoute.Name,
+			Description: policyRoute.Description,
+		}
+
+		explicit := make([]uint32, len(policyRoute.Rules))
+		for idx, rule := range policyRoute.Rules {
+			explicit[idx] = rule.Number
+		}
+		numbers, err := numberRules(explicit, 1, maxRuleNumber)
+		if err != nil {
+			return fmt.Errorf("policy route %s: %w", policyRoute.Name, err)
+		}
+
+		for idx, rule := range policyRoute.Rules {
+			if err := validatePolicyRouteRule(router.Firewall.Groups, rule); err != nil {
+				return fmt.Errorf("policy route %s rule %q: %w", policyRoute.Name, rule.Description, err)
+			}
+
+			table := rule.Table
+			if rule.NextHop.IsValid() {
+				if table == 0 {
+					table = nextHopTables[rule.NextHop]
+				}
+				if table == 0 {
+					table, err = freeTable(usedTables)
+					if err != nil {
+						return fmt.Errorf("policy route %s rule %q: %w", policyRoute.Name, rule.Description, err)
+					}
+					usedTables[table] = struct{}{}
+				}
+				if err := addDefaultRoute(rc, table, rule.NextHop); err != nil {
+					return fmt.Errorf("policy route %s rule %q: %w", policyRoute.Name, rule.Description, err)
+				}
+				nextHopTables[rule.NextHop] = table
+			}
+
+			modify.Rules = append(modify.Rules, edgeconfig.ModifyRule{
+				Number:      numbers[idx],
+				Action:      "modify",
+				Description: rule.Description,
+				Destination: rule.Destination,
+				Modify:      edgeconfig.ModifyAction{Table: table},
+				Protocol:    rule.Protocol,
+				Source:      rule.Source,
+			})
+		}
+
+		rc.Firewall.Modify = append(rc.Firewall.Modify, modify)
+
+		for _, ifaceName := range policyRoute.Interfaces {
+			if err := assignModify(rc, ifaceName, policyRoute.Name); err != nil {
+				return err
+			}
+		}
+	}
+
+	slices.SortFunc(rc.Protocols.Static.Tables, func(a, b edgeconfig.StaticTable) int {
+		return int(a.Table) - int(b.Table)
+	})
+
+	// Every table a rule routes with must have routes, otherwise matching traffic has nowhere to go
+	for _, modify := range rc.Firewall.Modify {
+		for _, rule := range modify.Rules {
+			if len(staticTable(rc, rule.Modify.Table).Routes) == 0 {
+				return fmt.Errorf("policy route %s rule %d uses table %d, which has no routes", modify.Name, rule.Number, rule.Modify.Table)
+			}
+		}
+	}
+
+	return nil
+}
+
+// validatePolicyRouteRule checks the rule has a target, and only matches ipv4 traffic on what modify rules support
+func validatePolicyRouteRule(groups config.FirewallGroups, rule config.PolicyRouteRule) error {
+	if rule.Table == 0 && !rule.NextHop.IsValid() {
+		return fmt.Errorf("a table or next-hop is required")
+	}
+	if rule.Table > maxRoutingTable {
+		return fmt.Errorf("table %d is outside of 1-%d", rule.Table, maxRoutingTable)
+	}
+	if rule.NextHop.IsValid() && !rule.NextHop.Is4() {
+		return fmt.Errorf("next-hop %s must be an ipv4 address, policy routes only support ipv4", rule.NextHop)
+	}
+
+	for _, ap := range []types.AddressPort{rule.Source, rule.Destination} {
+		if err := validateGroupRefs(groups, ap); err != nil {
+			return err
+		}
+		if ap.MACAddress != "" {
+			return fmt.Errorf("mac-address is not supported in policy routes")
+		}
+		if addressFamily(ap) == types.IPAddressTypeV6 || ap.Group.IPv6AddressGroup != "" || ap.Group.IPv6NetworkGroup != "" {
+			return fmt.Errorf("ipv6 addresses are not supported, policy routes only support ipv4")
+		}
+	}
+
+	return validatePorts(rule.Protocol, rule.Source, rule.Destination)
+}
+
+// freeTable returns the lowest routing table that isn't used
+func freeTable(used map[uint8]struct{}) (uint8, error) {
+	for table := uint8(1); table <= maxRoutingTable; table++ {
+		if _, ok := used[table]; !ok {
+			return table, nil
+		}
+	}
+	return 0, fmt.Errorf("no routing table left for the next-hop")
+}
+
+// staticTable returns the routing table, adding it to the static routes if it doesn't exist yet
+func staticTable(rc *edgeconfig.Router, table uint8) *edgeconfig.StaticTable {
+	tables := &rc.Protocols.Static.Tables
+	for idx := range *tables {
+		if (*tables)[idx].Table == table {
+			return &(*tables)[idx]
+		}
+	}
+	*tables = append(*tables, edgeconfig.StaticTable{Table: table})
+	return &(*tables)[len(*tables)-1]
+}
+
+// addDefaultRoute adds a default route through the next hop to the table, unless the table already has one through it
+// A table can only have one default route, so a different next hop for the same table is an error
+func addDefaultRoute(rc *edgeconfig.Router, table uint8, nextHop netip.Addr) error {
+	defaultRoute := netip.MustParsePrefix("0.0.0.0/0")
+	staticTbl := staticTable(rc, table)
+	for _, route := range staticTbl.Routes {
+		if route.Route != defaultRoute {
+			continue
+		}
+		if route.NextHop.NextHop != nextHop {
+			return fmt.Errorf("table %d already has a default route through %s, can't also route through %s", table, route.NextHop.NextHop, nextHop)
+		}
+		return nil
+	}
+
+	staticTbl.Routes = append(staticTbl.Routes, edgeconfig.StaticRoute{
+		Route:   defaultRoute,
+		NextHop: edgeconfig.NextHop{NextHop: nextHop},
+	})
+	return nil
+}
+
+// assignModify attaches the modify ruleset to traffic coming in on the interface
+func assignModify(rc *edgeconfig.Router, ifaceName, name string) error {
+	for ifaceIdx := range rc.Interfaces.Interfaces {
+		iface := &rc.Interfaces.Interfaces[ifaceIdx]
+		if iface.Name != ifaceName {
+			continue
+		}
+		if iface.Firewall.In.Modify != "" {
+			return fmt.Errorf("interface %s already has policy route %s, can't also assign %s", ifaceName, iface.Firewall.In.Modify, name)
+		}
+		iface.Firewall.In.Modify = name
+		return nil
+	}
+
+	if interfaceExists(rc, ifaceName) {
+		return fmt.Errorf("policy route %s: interface %s can't have rulesets attached, only ethernet interfaces can", name, ifaceName)
+	}
+	return fmt.Errorf("policy route %s: interface %s does not exist on the router", name, ifaceName)
+}
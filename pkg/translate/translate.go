@@ -8,20 +8,26 @@ import (
 	"github.com/cmmarslender/edgefig/pkg/types"
 )
 
-// ConfigToEdgeConfig translates the friendly config to edgerouter config
-// @TODO this should return a whole set of configs, not just router configs
-func ConfigToEdgeConfig(cfg *config.Config, interfaces map[string]struct{}) (*edgeconfig.Router, error) {
-	if len(cfg.Routers) == 0 {
-		return nil, fmt.Errorf("no routers configured")
+// ConfigToEdgeConfig translates the friendly config for a single router to edgerouter config
+// The rest of the config is used to look up shared items such as VLANs and firewall rulesets
+func ConfigToEdgeConfig(cfg *config.Config, router config.Router, interfaces map[string]struct{}) (*edgeconfig.Router, error) {
+	defaultRouter := getDefaultRouterConfig(interfaces)
+	err := applyFirewallOptions(defaultRouter, router.Firewall.Options)
+	if err != nil {
+		return nil, err
 	}
 
-	// @TODO Deal with more than the 0th router
-	router := cfg.Routers[0]
+	conntrack, err := translateConntrack(router.Conntrack)
+	if err != nil {
+		return nil, err
+	}
+	defaultRouter.System.Conntrack = conntrack
 
-	defaultRouter := getDefaultRouterConfig(interfaces)
-	defaultRouter.Firewall.AllPing = types.Enable
-	defaultRouter.Firewall.SendRedirects = types.Enable
-	defaultRouter.Firewall.SynCookies = types.Enable
+	offload, err := translateOffload(router.Offload)
+	if err != nil {
+		return nil, err
+	}
+	defaultRouter.System.Offload = offload
 
 	for intf, intCfg := range router.Interfaces {
 		_iface := edgeconfig.Interface{
@@ -88,109 +94,78 @@ func ConfigToEdgeConfig(cfg *config.Config, interfaces map[string]struct{}) (*ed
 	}
 
 	// Parse out firewall groups
-	for _, groupYML := range router.Firewall.Groups.AddressGroups {
-		defaultRouter.Firewall.Group.AddressGroups = append(defaultRouter.Firewall.Group.AddressGroups, edgeconfig.AddressGroup{
-			Name:        groupYML.Name,
-			AddressPort: groupYML.AddressPort,
-			Description: groupYML.Description,
-		})
+	firewallGroups, err := translateFirewallGroups(router.Firewall.Groups)
+	if err != nil {
+		return nil, err
 	}
+	defaultRouter.Firewall.Group = firewallGroups
 
 	// Parse out firewall zones/rules
 	for _, zoneYML := range router.Firewall.Zones {
-		namePrefix := ""
-		if zoneYML.IPType == types.IPAddressTypeV6 {
-			namePrefix = "ipv6-"
+		// Handles Rules, including any shared rulesets before and after the zone's own rules
+		zoneRules, err := expandZoneRules(cfg, zoneYML)
+		if err != nil {
+			return nil, err
 		}
-		_zone := edgeconfig.FirewallZone{
-			NamePrefix:    namePrefix,
-			Name:          zoneYML.Name,
-			DefaultAction: zoneYML.DefaultAction,
-			Description:   zoneYML.Description,
+		for _, ruleYML := range zoneRules {
+			for _, ap := range []types.AddressPort{ruleYML.Source, ruleYML.Destination} {
+				if err := validateGroupRefs(router.Firewall.Groups, ap); err != nil {
+					return nil, fmt.Errorf("firewall zone %s rule %q: %w", zoneYML.Name, ruleYML.Description, err)
+				}
+			}
+			if err := validatePorts(ruleYML.Protocol, ruleYML.Source, ruleYML.Destination); err != nil {
+				return nil, fmt.Errorf("firewall zone %s rule %q: %w", zoneYML.Name, ruleYML.Description, err)
+			}
+			if err := validateRuleMatches(ruleYML); err != nil {
+				return nil, fmt.Errorf("firewall zone %s rule %q: %w", zoneYML.Name, ruleYML.Description, err)
+			}
 		}
 
-		// Handles Rules
-		for _, ruleYML := range zoneYML.Rules {
-			_rule := edgeconfig.FirewallRule{
-				Action:      ruleYML.Action,
-				Description: ruleYML.Description,
-				Destination: ruleYML.Destination,
-				Log:         ruleYML.Log,
-				Protocol:    ruleYML.Protocol,
-				State: edgeconfig.FirewallRuleState{
-					Established: ruleYML.Established,
-					Invalid:     ruleYML.Invalid,
-					New:         ruleYML.New,
-					Related:     ruleYML.Related,
-				},
-				Source: ruleYML.Source,
+		// Zones with ip-type both are generated once per address family, from the rules that apply to that family
+		for _, family := range ZoneFamilies(zoneYML) {
+			namePrefix := ""
+			if family == types.IPAddressTypeV6 {
+				namePrefix = "ipv6-"
+			}
+			_zone := edgeconfig.FirewallZone{
+				NamePrefix:    namePrefix,
+				Name:          zoneYML.Name,
+				DefaultAction: zoneYML.DefaultAction,
+				Description:   zoneYML.Description,
 			}
 
-			_zone.Rules = append(_zone.Rules, _rule)
-		}
-
-		// Handles assignment of the zone to interfaces
-		for _, zoneIfaceName := range zoneYML.In {
-			for _ifaceIdx, _iface := range defaultRouter.Interfaces.Interfaces {
-				if _iface.Name == zoneIfaceName {
-					if zoneYML.IPType == types.IPAddressTypeV6 {
-						if _iface.Firewall.In.V6Name != "" {
-							panic("Duplicate zone assigned to interface")
-						}
-						defaultRouter.Interfaces.Interfaces[_ifaceIdx].Firewall.In.V6Name = zoneYML.Name
-						break
-
-					} else {
-						if _iface.Firewall.In.Name != "" {
-							panic("Duplicate zone assigned to interface")
-						}
-						defaultRouter.Interfaces.Interfaces[_ifaceIdx].Firewall.In.Name = zoneYML.Name
-						break
-					}
-				}
+			familyRules, err := ZoneRules(cfg, zoneYML, family)
+			if err != nil {
+				return nil, err
 			}
-		}
-		for _, zoneIfaceName := range zoneYML.Local {
-			for _ifaceIdx, _iface := range defaultRouter.Interfaces.Interfaces {
-				if _iface.Name == zoneIfaceName {
-					if zoneYML.IPType == types.IPAddressTypeV6 {
-						if _iface.Firewall.Local.V6Name != "" {
-							panic("Duplicate zone assigned to interface")
-						}
-						defaultRouter.Interfaces.Interfaces[_ifaceIdx].Firewall.Local.V6Name = zoneYML.Name
-						break
-					} else {
-						if _iface.Firewall.Local.Name != "" {
-							panic("Duplicate zone assigned to interface")
-						}
-						defaultRouter.Interfaces.Interfaces[_ifaceIdx].Firewall.Local.Name = zoneYML.Name
-						break
-					}
-				}
+			for _, zoneRule := range familyRules {
+				_rule := translateFirewallRule(zoneRule.Rule)
+				_rule.Number = zoneRule.Number
+				_zone.Rules = append(_zone.Rules, _rule)
 			}
-		}
-		for _, zoneIfaceName := range zoneYML.Out {
-			for _ifaceIdx, _iface := range defaultRouter.Interfaces.Interfaces {
-				if _iface.Name == zoneIfaceName {
-					if zoneYML.IPType == types.IPAddressTypeV6 {
-						if _iface.Firewall.Out.V6Name != "" {
-							panic("Duplicate zone assigned to interface")
-						}
-						defaultRouter.Interfaces.Interfaces[_ifaceIdx].Firewall.Out.V6Name = zoneYML.Name
-						break
-					} else {
-						if _iface.Firewall.Out.Name != "" {
-							panic("Duplicate zone assigned to interface")
-						}
-						defaultRouter.Interfaces.Interfaces[_ifaceIdx].Firewall.Out.Name = zoneYML.Name
-						break
+
+			defaultRouter.Firewall.Zones = append(defaultRouter.Firewall.Zones, _zone)
+
+			// Handles assignment of the zone to interfaces
+			for _, assignment := range []struct {
+				direction  string
+				interfaces []string
+			}{{"in", zoneYML.In}, {"out", zoneYML.Out}, {"local", zoneYML.Local}} {
+				for _, ifaceName := range assignment.interfaces {
+					err = assignFirewallZone(defaultRouter, ifaceName, assignment.direction, family, zoneYML.Name)
+					if err != nil {
+						return nil, err
 					}
 				}
 			}
 		}
+	}
 
-		defaultRouter.Firewall.Zones = append(defaultRouter.Firewall.Zones, _zone)
+	zonePolicy, err := translateZonePolicy(defaultRouter, router.Firewall)
+	if err != nil {
+		return nil, err
 	}
+	defaultRouter.ZonePolicy = zonePolicy
 
 	for _, bgpCfg := range router.BGP {
 		edgeBGPConfig := edgeconfig.BGPConfig{
@@ -347,9 +322,19 @@ func ConfigToEdgeConfig(cfg *config.Config, interfaces map[string]struct{}) (*ed
 			edgeRouteConfig.RouteSuffix = "6"
 		}
 
+		if staticRoute.Table != 0 {
+			table := staticTable(defaultRouter, staticRoute.Table)
+			table.Routes = append(table.Routes, edgeRouteConfig)
+			continue
+		}
 		defaultRouter.Protocols.Static.Routes = append(defaultRouter.Protocols.Static.Routes, edgeRouteConfig)
 	}
 
+	err = translatePolicyRoutes(defaultRouter, router)
+	if err != nil {
+		return nil, err
+	}
+
 	_dhcpServer := edgeconfig.DHCPServer{
 		Disabled:       len(router.DHCP) == 0,
 		HostfileUpdate: false,
@@ -397,7 +382,19 @@ func ConfigToEdgeConfig(cfg *config.Config, interfaces map[string]struct{}) (*ed
 
 	_natService := edgeconfig.NatService{}
 	for _, natRule := range router.NAT {
+		for _, ap := range []types.AddressPort{natRule.InsideAddress, natRule.OutsideAddress} {
+			if err := validateGroupRefs(router.Firewall.Groups, ap); err != nil {
+				return nil, fmt.Errorf("nat rule %s: %w", natRule.Name, err)
+			}
+		}
+		if err := validatePorts(natRule.Protocol, natRule.InsideAddress, natRule.OutsideAddress); err != nil {
+			return nil, fmt.Errorf("nat rule %s: %w", natRule.Name, err)
+		}
+		if natRule.InsideAddress.MACAddress != "" || natRule.OutsideAddress.MACAddress != "" {
+			return nil, fmt.Errorf("nat rule %s: mac-address is only supported in firewall rules", natRule.Name)
+		}
 		newRule := edgeconfig.NatRule{
+			Number:            natRule.Number,
 			Name:              natRule.Name,
 			Type:              natRule.Type,
 			InboundInterface:  natRule.InboundInterface,
@@ -416,6 +413,14 @@ func ConfigToEdgeConfig(cfg *config.Config, interfaces map[string]struct{}) (*ed
 		}
 
 	}
+	err = numberNATRules(_natService.Dest, 1, natSourceRuleStart-1)
+	if err != nil {
+		return nil, fmt.Errorf("destination nat: %w", err)
+	}
+	err = numberNATRules(_natService.Src, natSourceRuleStart, maxRuleNumber)
+	if err != nil {
+		return nil, fmt.Errorf("source nat: %w", err)
+	}
 	defaultRouter.Service.NAT = _natService
 
 	defaultRouter.System.HostName = router.Name
@@ -0,0 +1,152 @@
+package translate
+
+import (
+	"fmt"
+
+	"github.com/cmmarslender/edgefig/pkg/config"
+	"github.com/cmmarslender/edgefig/pkg/edgeconfig"
+	"github.com/cmmarslender/edgefig/pkg/types"
+)
+
+// translateZonePolicy converts the zone based firewall config
+// Every interface can be in at most one zone, and rulesets are referenced by the name of a firewall zone of the matching ip type
+// Zone interfaces must exist on the router, and can't also have in, out or local rulesets, since EdgeOS rejects both
+func translateZonePolicy(rc *edgeconfig.Router, firewall config.Firewall) (edgeconfig.ZonePolicy, error) {
+	var zonePolicy edgeconfig.ZonePolicy
+
+	zoneNames := map[string]struct{}{}
+	interfaceZones := map[string]string{}
+	localZone := ""
+	for _, zone := range firewall.ZonePolicy {
+		if zone.Name == "" {
+			return zonePolicy, fmt.Errorf("zone-policy zone is missing a name")
+		}
+		if _, ok := zoneNames[zone.Name]; ok {
+			return zonePolicy, fmt.Errorf("duplicate zone-policy zone %s", zone.Name)
+		}
+		zoneNames[zone.Name] = struct{}{}
+
+		if zone.Local {
+			if localZone != "" {
+				return zonePolicy, fmt.Errorf("zone-policy zones %s and %s are both the local zone", localZone, zone.Name)
+			}
+			if len(zone.Interfaces) > 0 {
+				return zonePolicy, fmt.Errorf("zone-policy zone %s is the local zone, so it can't have interfaces", zone.Name)
+			}
+			localZone = zone.Name
+		}
+
+		for _, iface := range zone.Interfaces {
+			if other, ok := interfaceZones[iface]; ok {
+				return zonePolicy, fmt.Errorf("interface %s is in zone-policy zones %s and %s, but can only be in one zone", iface, other, zone.Name)
+			}
+			if !interfaceExists(rc, iface) {
+				return zonePolicy, fmt.Errorf("zone-policy zone %s: interface %s does not exist on the router", zone.Name, iface)
+			}
+			if direction := assignedDirection(rc, iface); direction != "" {
+				return zonePolicy, fmt.Errorf("zone-policy zone %s: interface %s also has a firewall ruleset for %s traffic, an interface can't use both", zone.Name, iface, direction)
+			}
+			interfaceZones[iface] = zone.Name
+		}
+	}
+
+	for _, zone := range firewall.ZonePolicy {
+		defaultAction := zone.DefaultAction
+		if defaultAction == "" {
+			defaultAction = "drop"
+		}
+
+		_zone := edgeconfig.PolicyZone{
+			Name:          zone.Name,
+			DefaultAction: defaultAction,
+			Description:   zone.Description,
+			Interfaces:    zone.Interfaces,
+			LocalZone:     types.KeyWhenEnabled(zone.Local),
+		}
+
+		for _, from := range zone.From {
+			if _, ok := zoneNames[from.Zone]; !ok || from.Zone == zone.Name {
+				return zonePolicy, fmt.Errorf("zone-policy zone %s: from zone %s must be another zone-policy zone", zone.Name, from.Zone)
+			}
+			if from.Firewall == "" && from.IPv6Firewall == "" {
+				return zonePolicy, fmt.Errorf("zone-policy zone %s: from zone %s needs a firewall or ipv6-firewall", zone.Name, from.Zone)
+			}
+			if err := checkRulesetType(firewall, from.Firewall, types.IPAddressTypeV4); err != nil {
+				return zonePolicy, fmt.Errorf("zone-policy zone %s from %s: %w", zone.Name, from.Zone, err)
+			}
+			if err := checkRulesetType(firewall, from.IPv6Firewall, types.IPAddressTypeV6); err != nil {
+				return zonePolicy, fmt.Errorf("zone-policy zone %s from %s: %w", zone.Name, from.Zone, err)
+			}
+
+			_zone.From = append(_zone.From, edgeconfig.PolicyZoneFrom{
+				Zone: from.Zone,
+				Firewall: edgeconfig.InterfaceFirewallZone{
+					Name:   from.Firewall,
+					V6Name: from.IPv6Firewall,
+				},
+			})
+		}
+
+		zonePolicy.Zones = append(zonePolicy.Zones, _zone)
+	}
+
+	return zonePolicy, nil
+}
+
+// interfaceExists returns true if the router has the ethernet or switch interface, or vif such as eth1.10
+func interfaceExists(rc *edgeconfig.Router, name string) bool {
+	for _, iface := range rc.Interfaces.Interfaces {
+		if iface.Name == name {
+			return true
+		}
+		for _, vlan := range iface.VLANs {
+			if fmt.Sprintf("%s.%d", iface.Name, vlan.ID) == name {
+				return true
+			}
+		}
+	}
+	for _, iface := range rc.Interfaces.Switches {
+		if iface.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// assignedDirection returns the first direction (in, out, or local) the interface has a firewall ruleset for, if any
+func assignedDirection(rc *edgeconfig.Router, name string) string {
+	for _, iface := range rc.Interfaces.Interfaces {
+		if iface.Name != name {
+			continue
+		}
+		for _, assignment := range []struct {
+			direction string
+			zone      edgeconfig.InterfaceFirewallZone
+		}{{"in", iface.Firewall.In}, {"out", iface.Firewall.Out}, {"local", iface.Firewall.Local}} {
+			if assignment.zone.Name != "" || assignment.zone.V6Name != "" {
+				return assignment.direction
+			}
+		}
+	}
+	return ""
+}
+
+// checkRulesetType checks that the named firewall zone exists with the expected ip type
+// An empty name is allowed, since each from zone can leave out one of the ip types
+func checkRulesetType(firewall config.Firewall, name string, ipType types.IPAddressType) error {
+	if name == "" {
+		return nil
+	}
+	for _, zone := range firewall.Zones {
+		if zone.Name != name {
+			continue
+		}
+		for _, family := range ZoneFamilies(zone) {
+			if family == ipType {
+				return nil
+			}
+		}
+		return fmt.Errorf("firewall zone %s is not %s", name, ipType)
+	}
+	return fmt.Errorf("could not find firewall zone %s", name)
+}
@@ -0,0 +1,71 @@
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+var firmwareVersionRegex = regexp.MustCompile(`^v?(\d+)\.(\d+)\.(\d+)`)
+
+// FirmwareVersion is an EdgeOS firmware version, such as v2.0.9-hotfix.4
+// Only the major, minor, and patch numbers are used for comparisons
+type FirmwareVersion struct {
+	Major int
+	Minor int
+	Patch int
+	Raw   string
+}
+
+// ParseFirmwareVersion parses a version string from `show version`
+func ParseFirmwareVersion(version string) (FirmwareVersion, error) {
+	matches := firmwareVersionRegex.FindStringSubmatch(version)
+	if matches == nil {
+		return FirmwareVersion{}, fmt.Errorf("unrecognized firmware version %s", version)
+	}
+
+	// The regex guarantees these are numbers
+	major, _ := strconv.Atoi(matches[1])
+	minor, _ := strconv.Atoi(matches[2])
+	patch, _ := strconv.Atoi(matches[3])
+
+	return FirmwareVersion{Major: major, Minor: minor, Patch: patch, Raw: version}, nil
+}
+
+// MustParseFirmwareVersion is ParseFirmwareVersion, but panics on error. Intended for constants
+func MustParseFirmwareVersion(version string) FirmwareVersion {
+	v, err := ParseFirmwareVersion(version)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// IsZero returns true if the version is unknown
+func (v FirmwareVersion) IsZero() bool {
+	return v.Raw == "" && v.Major == 0 && v.Minor == 0 && v.Patch == 0
+}
+
+// AtLeast returns true if this version is the same as or newer than other
+func (v FirmwareVersion) AtLeast(other FirmwareVersion) bool {
+	if v.Major != other.Major {
+		return v.Major > other.Major
+	}
+	if v.Minor != other.Minor {
+		return v.Minor > other.Minor
+	}
+	return v.Patch >= other.Patch
+}
+
+func (v FirmwareVersion) String() string {
+	if v.Raw != "" {
+		return v.Raw
+	}
+	return fmt.Sprintf("v%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// MarshalJSON marshals the version as its string form
+func (v FirmwareVersion) MarshalJSON() ([]byte, error) {
+	return json.Marshal(v.String())
+}
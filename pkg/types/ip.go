@@ -16,6 +16,8 @@ const (
 	IPAddressTypeV4 IPAddressType = "ipv4"
 	// IPAddressTypeV6 ipv6 addresses
 	IPAddressTypeV6 IPAddressType = "ipv6"
+	// IPAddressTypeBoth is used for firewall zones that apply to both ipv4 and ipv6
+	IPAddressTypeBoth IPAddressType = "both"
 )
 
 // AddressPort is the address config block for a NAT rule
@@ -24,7 +26,9 @@ type AddressPort struct {
 	Prefix  netip.Prefix `yaml:"prefix" edge:"address,omitempty"`
 	Range   AddressRange `yaml:"range" edge:"address,omitempty"`
 	Group   AddressGroup `yaml:",inline" edge:"group,omitempty"`
-	Port    uint16       `yaml:"port" edge:"port,omitempty"`
+	// MACAddress is only supported as a firewall rule source
+	MACAddress string `yaml:"mac-address" edge:"mac-address,omitempty"`
+	Port       Ports  `yaml:"port" edge:"port,omitempty"`
 }
 
 // AddressRange enables address ranges like 10.0.0.1-10.0.0.5
@@ -33,9 +37,66 @@ type AddressRange struct {
 	End   netip.Addr
 }
 
-// AddressGroup used to specify an IP by group
+// AddressGroup used to specify addresses or ports by group
+// Only one group of each kind can be used per source/destination
 type AddressGroup struct {
-	AddressGroup string `yaml:"address-group" edge:"address-group"`
+	AddressGroup     string `yaml:"address-group" edge:"address-group,omitempty"`
+	IPv6AddressGroup string `yaml:"ipv6-address-group" edge:"ipv6-address-group,omitempty"`
+	IPv6NetworkGroup string `yaml:"ipv6-network-group" edge:"ipv6-network-group,omitempty"`
+	NetworkGroup     string `yaml:"network-group" edge:"network-group,omitempty"`
+	PortGroup        string `yaml:"port-group" edge:"port-group,omitempty"`
+}
+
+// GroupAddress is a single member of an address group, either an address (10.0.0.1) or a range (10.0.0.1-10.0.0.5)
+type GroupAddress struct {
+	Start netip.Addr
+	End   netip.Addr
+}
+
+// Is6 returns true if the member is an ipv6 address or range
+func (g GroupAddress) Is6() bool {
+	return g.Start.Is6()
+}
+
+// UnmarshalYAML unmarshals either a single address or a range
+func (g *GroupAddress) UnmarshalYAML(value *yaml.Node) error {
+	var v string
+	if err := value.Decode(&v); err != nil {
+		return err
+	}
+
+	if !strings.Contains(v, "-") {
+		addr, err := netip.ParseAddr(v)
+		if err != nil {
+			return fmt.Errorf("invalid group address: %v", err)
+		}
+		g.Start = addr
+		return nil
+	}
+
+	var addrRange AddressRange
+	if err := addrRange.UnmarshalYAML(value); err != nil {
+		return err
+	}
+	if addrRange.Start.Is6() != addrRange.End.Is6() {
+		return fmt.Errorf("invalid range %s: start and end must be the same address family", v)
+	}
+	g.Start = addrRange.Start
+	g.End = addrRange.End
+	return nil
+}
+
+// MarshalEdge marshals the member as an address or start-end range
+func (g GroupAddress) MarshalEdge() ([]byte, error) {
+	if !g.End.IsValid() {
+		return []byte(g.Start.String()), nil
+	}
+	return []byte(fmt.Sprintf("%s-%s", g.Start.String(), g.End.String())), nil
+}
+
+// MarshalEdgeWithDepth not used for GroupAddress
+func (g GroupAddress) MarshalEdgeWithDepth(depth int) ([]byte, error) {
+	return nil, fmt.Errorf("marshaledgewithdepth not implemented for GroupAddress")
 }
 
 // UnmarshalYAML unmarshals the range 10.0.0.1-10.0.0.5 to the struct representing the Start/End
@@ -64,3 +125,11 @@ func (a *AddressRange) UnmarshalYAML(value *yaml.Node) error {
 	a.End = endIP
 	return nil
 }
+
+// MarshalYAML marshals the range back to the 10.0.0.1-10.0.0.5 format
+func (a AddressRange) MarshalYAML() (interface{}, error) {
+	if !a.Start.IsValid() && !a.End.IsValid() {
+		return "", nil
+	}
+	return fmt.Sprintf("%s-%s", a.Start.String(), a.End.String()), nil
+}
@@ -0,0 +1,115 @@
+package types
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+var serviceNameRegex = regexp.MustCompile(`^[a-z][a-z0-9-]*$`)
+
+// Port is a single port (443), port range (8000-8100), or service name from /etc/services (https)
+type Port string
+
+// ParsePort validates and returns the port
+func ParsePort(port string) (Port, error) {
+	port = strings.TrimSpace(port)
+
+	if start, end, isRange := strings.Cut(port, "-"); isRange && !serviceNameRegex.MatchString(port) {
+		startNum, err := parsePortNumber(start)
+		if err != nil {
+			return "", fmt.Errorf("invalid port range %s: %w", port, err)
+		}
+		endNum, err := parsePortNumber(end)
+		if err != nil {
+			return "", fmt.Errorf("invalid port range %s: %w", port, err)
+		}
+		if startNum > endNum {
+			return "", fmt.Errorf("invalid port range %s: start is after end", port)
+		}
+		return Port(port), nil
+	}
+
+	if _, err := strconv.Atoi(port); err == nil {
+		if _, err := parsePortNumber(port); err != nil {
+			return "", err
+		}
+		return Port(port), nil
+	}
+
+	if !serviceNameRegex.MatchString(port) {
+		return "", fmt.Errorf("invalid port %s: expected a port number, range, or service name", port)
+	}
+	return Port(port), nil
+}
+
+func parsePortNumber(port string) (uint16, error) {
+	num, err := strconv.ParseUint(port, 10, 16)
+	if err != nil || num == 0 {
+		return 0, fmt.Errorf("invalid port number %s", port)
+	}
+	return uint16(num), nil
+}
+
+// UnmarshalYAML accepts the port as a number or a string
+func (p *Port) UnmarshalYAML(value *yaml.Node) error {
+	var v string
+	if err := value.Decode(&v); err != nil {
+		return err
+	}
+
+	port, err := ParsePort(v)
+	if err != nil {
+		return err
+	}
+	*p = port
+	return nil
+}
+
+// Ports is a list of ports, port ranges, and service names, marshalled to EdgeOS's comma separated form (80,443,8000-8100)
+type Ports []Port
+
+// UnmarshalYAML accepts a single port, a comma separated string of ports, or a list of ports
+func (p *Ports) UnmarshalYAML(value *yaml.Node) error {
+	var values []string
+	switch value.Kind {
+	case yaml.SequenceNode:
+		if err := value.Decode(&values); err != nil {
+			return err
+		}
+	default:
+		var v string
+		if err := value.Decode(&v); err != nil {
+			return err
+		}
+		values = strings.Split(v, ",")
+	}
+
+	ports := Ports{}
+	for _, v := range values {
+		port, err := ParsePort(v)
+		if err != nil {
+			return err
+		}
+		ports = append(ports, port)
+	}
+	*p = ports
+	return nil
+}
+
+// MarshalEdge marshals the ports to a comma separated list
+func (p Ports) MarshalEdge() ([]byte, error) {
+	values := make([]string, len(p))
+	for idx, port := range p {
+		values[idx] = string(port)
+	}
+	return []byte(strings.Join(values, ",")), nil
+}
+
+// MarshalEdgeWithDepth not used for Ports
+func (p Ports) MarshalEdgeWithDepth(depth int) ([]byte, error) {
+	return nil, fmt.Errorf("marshaledgewithdepth not implemented for Ports")
+}
@@ -10,4 +10,15 @@ const (
 	ProtocolTCP Protocol = "tcp"
 	// ProtocolUDP for udp only
 	ProtocolUDP Protocol = "udp"
+	// ProtocolTCPUDP for both tcp and udp
+	ProtocolTCPUDP Protocol = "tcp_udp"
+	// ProtocolICMP for icmp (ipv4 only)
+	ProtocolICMP Protocol = "icmp"
+	// ProtocolICMPv6 for icmpv6 (ipv6 only)
+	ProtocolICMPv6 Protocol = "icmpv6"
 )
+
+// HasPorts returns true if the protocol has ports that rules can match on
+func (p Protocol) HasPorts() bool {
+	return p == ProtocolTCP || p == ProtocolUDP || p == ProtocolTCPUDP
+}